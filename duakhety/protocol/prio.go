@@ -23,6 +23,7 @@ type Prio struct {
 	*onet.TreeNodeInstance
 	// input fields
 	configFile *string
+	// shares are the client requests of every server, added by AddShares
 	shares 	[][]*mpc.ClientRequest
 	ns int
 	np int
@@ -83,7 +84,10 @@ func (p *Prio) Start() error {
 		return errors.New("couldn't load prio configurations from file")
 	}
 
-	p.shares = TransposeClientRequest(p.shares, p.np, p.ns)
+	if len(p.shares) != p.ns {
+		p.finish(false)
+		return errors.New("please add the shares of the data points")
+	}
 	l := p.List()
 	for i, _ := range l {
 		err := p.SendTo(l[i], &EvalCircuit{
@@ -98,6 +102,23 @@ func (p *Prio) Start() error {
 	return nil
 }
 
+// AddShares adds the client requests of a data point, one per server, to
+// those sent to the servers when the protocol starts. The servers check all
+// the points of a round together, so every request is kept until then and
+// the memory grows with the dataset, only its points aren't kept.
+func (p *Prio) AddShares(shares []*mpc.ClientRequest) error {
+	if len(shares) != p.ns {
+		return errors.New("a data point needs a share per server")
+	}
+	if p.shares == nil {
+		p.shares = make([][]*mpc.ClientRequest, p.ns)
+	}
+	for i, share := range shares {
+		p.shares[i] = append(p.shares[i], share)
+	}
+	p.np++
+	return nil
+}
 
 func (p *Prio) evalCircuit(e structEvalCircuit) error {
 	// What's my index?
@@ -340,16 +361,4 @@ func TransposeOutShare(a [][]*mpc.OutShare, n int, m int) [][]*mpc.OutShare {
 		}
 	}
 	return b
-}
-
-// https://stackoverflow.com/questions/38297882/cant-swap-elements-of-2d-array-slice-using-golang
-func TransposeClientRequest(a [][]*mpc.ClientRequest, n int, m int) [][]*mpc.ClientRequest {
-	b := make([][]*mpc.ClientRequest, m)
-	for i := 0; i < m; i++ {
-		b[i] = make([]*mpc.ClientRequest, n)
-		for j := 0; j < n; j++ {
-			b[i][j] = a[j][i]
-		}
-	}
-	return b
 }
\ No newline at end of file
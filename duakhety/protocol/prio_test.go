@@ -2,6 +2,9 @@ package protocol
 
 import (
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/dedis/onet"
 	"github.com/dedis/onet/log"
 	"github.com/stretchr/testify/require"
+	"github.com/henrycg/prio/config"
 	"github.com/henrycg/prio/mpc"
 	"github.com/dedis/student_18_ml/vanilla"
 )
@@ -44,13 +48,23 @@ func prio(t *testing.T, nbrNodes int, threshold int) {
 
 	// Prio configuration file
 	configFileName := "test.conf"
-	// Dataset file name
-	datasetFileName := "../../data/sample.csv"
-	// Generate all the client requests
-	shares, error := GetSharesFromCSV(datasetFileName, configFileName)
-	require.Nil(t, error)
-	require.NotNil(t, shares)
-
+	// Generate a dataset with a single integer feature, as in the config
+	dir, err := ioutil.TempDir("", "prio")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	datasetFileName := filepath.Join(dir, "linear.csv")
+	dataset, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      20,
+		Intercept: 3,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x", Coefficient: 2, Max: 100},
+		},
+		Noise:   0.5,
+		Integer: true,
+		Seed:    1,
+	})
+	require.Nil(t, err)
+	require.Nil(t, dataset.WriteCSV(datasetFileName))
 
 	services := local.GetServices(servers, testServiceID)
 
@@ -59,8 +73,13 @@ func prio(t *testing.T, nbrNodes int, threshold int) {
 
 	protocol := pi.(*Prio)
 	protocol.configFile = &configFileName
-	protocol.shares = shares
-	protocol.np = len(shares)
+	// Generate the client requests, which the protocol keeps one point at a
+	// time
+	err = GetSharesFromCSV(datasetFileName, configFileName, nil, nil,
+		protocol.AddShares)
+	require.Nil(t, err)
+	count := protocol.np
+	require.Equal(t, len(dataset.Points), count)
 	require.Nil(t, protocol.Start())
 
 	select {
@@ -79,11 +98,20 @@ func prio(t *testing.T, nbrNodes int, threshold int) {
 	}
 
 	log.Printf("Model built: %s", finalAggregator.String())
+	cfg := config.LoadFile(configFileName)
+	require.NotNil(t, cfg)
+	model, err := DecodeLinReg(finalAggregator, cfg, count, nil, nil)
+	require.Nil(t, err)
+	log.Printf("Decoded: %s", model.Describe())
 	points, err := vanilla.GetDataPointsFromCSV(datasetFileName)
 	require.Nil(t, err)
 	r, err := vanilla.TrainRegressionModel(points)
 	require.Nil(t, err)
 	log.Printf("Normally: ", r.Formula)
+	require.InDelta(t, dataset.Coefficients[0], r.Coeff(1), 0.1)
+	for i, c := range model.Coefficients {
+		require.InDelta(t, r.Coeff(i), c, 1e-6)
+	}
 }
 
 
@@ -8,13 +8,19 @@ import (
 	"github.com/henrycg/prio/share"
 	"github.com/henrycg/prio/triple"
 	"github.com/dedis/onet/log"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 )
 
-// Similar to mpc.client (RandomRequest)
+// Similar to mpc.client (RandomRequest). The values are encoded by encoding,
+// see vanilla.PrioSuggestion.Encode, or must be nonnegative integers if it is
+// nil.
 func GetSharesFromDataPoint(label float64, features []float64,
-	cfg *config.Config) ([]*mpc.ClientRequest, error) {
+	cfg *config.Config, encoding *vanilla.PrioSuggestion) (
+	[]*mpc.ClientRequest, error) {
 
-	features_count := len(features)
 	// Number of servers
 	ns := cfg.NumServers()
 	// Share for each server
@@ -32,16 +38,18 @@ func GetSharesFromDataPoint(label float64, features []float64,
 		out[s] = new(mpc.ClientRequest)
 	}
 
-	inputs_before := make([]*big.Int, features_count + 1)
-
-	for i, _:= range features {
-		inputs_before[i] = big.NewInt(int64(features[i]))
+	inputs_before, err := prioIntegers(label, features, encoding)
+	if err != nil {
+		return nil, err
+	}
+	for i, _:= range inputs_before {
 		log.Printf("%d:%s", i, inputs_before[i].String())
 	}
-	// The label
-	inputs_before[features_count] = big.NewInt(int64(label))
-	log.Printf("%d:%s", features_count, inputs_before[features_count].String())
-	inputs := mpc.LinReg_New(&cfg.Fields[0], inputs_before)
+	field, err := linRegField(cfg)
+	if err != nil {
+		return nil, err
+	}
+	inputs := mpc.LinReg_New(field, inputs_before)
 
 	// Evaluate the Valid() circuit
 	ckt := mpc.ConfigToCircuit(cfg)
@@ -62,20 +70,262 @@ func GetSharesFromDataPoint(label float64, features []float64,
 	return out, nil
 }
 
-func GetSharesFromCSV(datasetFile string, configFile string) (shares [][]*mpc.ClientRequest, err error) {
-	points, err := vanilla.GetDataPointsFromCSV(datasetFile)
-	if err != nil {
-		return nil, err
+// prioIntegers returns the integers aggregated by Prio for the features and
+// the label of a point, encoded by encoding or, if it is nil, required to be
+// nonnegative integers rather than truncated
+func prioIntegers(label float64, features []float64,
+	encoding *vanilla.PrioSuggestion) ([]*big.Int, error) {
+	values := make([]*big.Int, len(features)+1)
+	if encoding != nil {
+		encoded, err := encoding.Encode(label, features)
+		if err != nil {
+			return nil, err
+		}
+		for i, e := range encoded {
+			values[i] = big.NewInt(e)
+		}
+		return values, nil
+	}
+	for i, v := range append(append([]float64{}, features...), label) {
+		if v < 0 || v != math.Trunc(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("prio aggregates nonnegative integers, "+
+				"not %g, the values need an encoding", v)
+		}
+		values[i] = big.NewInt(int64(v))
+	}
+	return values, nil
+}
+
+// GetSharesFromCSV generates the client requests of every data point of a
+// dataset file of any supported format, loaded with the given options, which
+// may be nil. The values are encoded by encoding, which may be nil, and
+// checked against the config. The requests of each point are given to emit
+// as soon as they are generated, see GetSharesFromSource.
+func GetSharesFromCSV(datasetFile string, configFile string,
+	opts *vanilla.LoadOptions, encoding *vanilla.PrioSuggestion,
+	emit func(shares []*mpc.ClientRequest) error) error {
+	// Scaled features are fractions, which Prio can't aggregate
+	if opts != nil && opts.Scaler != nil {
+		return errors.New("prio can't aggregate scaled features")
 	}
 	// Create a config from the config file
 	cfg := config.LoadFile(configFile)
-	// Create shares slice
-	shares = make([][]*mpc.ClientRequest, len(points))
-	for i, point := range points {
-		shares[i], err = GetSharesFromDataPoint(point.Observed, point.Variables, cfg)
+	if cfg == nil {
+		return errors.New("couldn't load prio configurations from " +
+			configFile)
+	}
+	if encoding != nil {
+		if err := CheckLinRegBits(cfg, encoding); err != nil {
+			return err
+		}
+	}
+	source, err := vanilla.OpenDataSource(datasetFile, opts)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	return GetSharesFromSource(source, cfg, encoding, emit)
+}
+
+// GetSharesFromSource generates the client requests of the data points of a
+// source, which are read one at a time and encoded by encoding, which may be
+// nil. The requests of each point, one per server, are given to emit. The
+// points aren't kept, but Prio.AddShares keeps their requests until the
+// protocol starts.
+func GetSharesFromSource(source vanilla.DataSource, cfg *config.Config,
+	encoding *vanilla.PrioSuggestion,
+	emit func(shares []*mpc.ClientRequest) error) error {
+	if encoding != nil {
+		columns := source.Columns()
+		names := append(append([]string{}, columns.Features...),
+			columns.Label)
+		matching := len(names) == len(encoding.Columns)
+		for i := 0; matching && i < len(names); i++ {
+			matching = names[i] == encoding.Columns[i]
+		}
+		if !matching {
+			return fmt.Errorf("dataset columns %v don't match the encoded "+
+				"columns %v", names, encoding.Columns)
+		}
+	}
+	for {
+		point, err := source.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		shares, err := GetSharesFromDataPoint(point.Label, point.Variables,
+			cfg, encoding)
+		if err != nil {
+			return err
+		}
+		if err := emit(shares); err != nil {
+			return err
+		}
+	}
+}
+
+// linRegField returns the linear regression field of a prio configuration,
+// which is its first field
+func linRegField(cfg *config.Config) (*config.Field, error) {
+	if len(cfg.Fields) == 0 {
+		return nil, errors.New("prio configuration has no field")
+	}
+	if cfg.Fields[0].Type != "linReg" {
+		return nil, fmt.Errorf("prio field %q has type %q, not linReg",
+			cfg.Fields[0].Name, cfg.Fields[0].Type)
+	}
+	return &cfg.Fields[0], nil
+}
+
+// CheckLinRegBits returns an error if the linear regression field of a prio
+// configuration is too narrow for the values of a dataset, as profiled by
+// vanilla.Profile.SuggestPrio. Values wider than their linRegBits are
+// silently truncated and sums wider than the field wrap around.
+func CheckLinRegBits(cfg *config.Config, s *vanilla.PrioSuggestion) error {
+	field, err := linRegField(cfg)
+	if err != nil {
+		return err
+	}
+	bits := field.LinRegBits
+	if len(bits) != len(s.LinRegBits) {
+		return fmt.Errorf("linRegBits has %d widths for %d columns",
+			len(bits), len(s.LinRegBits))
+	}
+	for i, width := range s.LinRegBits {
+		if bits[i] < width {
+			return fmt.Errorf("column %q needs %d bits, linRegBits gives %d",
+				s.Columns[i], width, bits[i])
+		}
+	}
+	if s.AccumulatorBits >= share.IntModulus.BitLen() {
+		return fmt.Errorf("aggregates need %d bits, the field has %d",
+			s.AccumulatorBits, share.IntModulus.BitLen())
+	}
+	return nil
+}
+
+// LinRegMoments returns the XᵀX and Xᵀy moments of the count points
+// aggregated by the linear regression field of a prio configuration, X having
+// a leading column of ones for the intercept. For the features x_1..x_d and
+// the label y of every point, the field aggregates, in order, the sums of
+// x_1..x_d and y, of x_i * x_j for i <= j and of x_i * y.
+func LinRegMoments(agg *mpc.Aggregator, cfg *config.Config, count int) (
+	xtx [][]float64, xty []float64, err error) {
+	field, err := linRegField(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := len(field.LinRegBits) - 1
+	if d < 1 {
+		return nil, nil, errors.New("linRegBits has no feature")
+	}
+	expected := d + 1 + d*(d+1)/2 + d
+	if agg == nil || len(agg.Values) < expected {
+		return nil, nil, fmt.Errorf("aggregator has fewer than %d values",
+			expected)
+	}
+	// The values aggregated are nonnegative, so a sum in the upper half of
+	// the field wrapped around it
+	half := new(big.Int).Rsh(share.IntModulus, 1)
+	values := make([]float64, expected)
+	for i := range values {
+		if agg.Values[i].Sign() < 0 || agg.Values[i].Cmp(half) > 0 {
+			return nil, nil, errors.New("aggregates overflow the prio field")
+		}
+		values[i], _ = new(big.Float).SetInt(agg.Values[i]).Float64()
+	}
+
+	n := d + 1
+	xtx = make([][]float64, n)
+	for i := range xtx {
+		xtx[i] = make([]float64, n)
+	}
+	xty = make([]float64, n)
+	xtx[0][0] = float64(count)
+	for i := 0; i < d; i++ {
+		xtx[0][i+1], xtx[i+1][0] = values[i], values[i]
+	}
+	xty[0] = values[d]
+	next := d + 1
+	for i := 0; i < d; i++ {
+		for j := i; j < d; j++ {
+			xtx[i+1][j+1], xtx[j+1][i+1] = values[next], values[next]
+			next++
+		}
+	}
+	for i := 0; i < d; i++ {
+		xty[i+1] = values[next]
+		next++
+	}
+	return xtx, xty, nil
+}
+
+// DecodeLinReg solves the normal equations of the count points aggregated by
+// the linear regression field of a prio configuration, see LinRegMoments.
+// The points were encoded by encoding, which may be nil, and the moments are
+// decoded back to the original units. The coefficients are those of
+// vanilla.TrainRegressionModel on the same points, up to the rounding of the
+// encoding. columns may be nil.
+func DecodeLinReg(agg *mpc.Aggregator, cfg *config.Config, count int,
+	encoding *vanilla.PrioSuggestion, columns *vanilla.Columns) (
+	*vanilla.LinearModel, error) {
+	xtx, xty, err := LinRegMoments(agg, cfg, count)
+	if err != nil {
+		return nil, err
+	}
+	if encoding != nil {
+		xtx, xty, err = decodeMoments(xtx, xty, encoding)
 		if err != nil {
 			return nil, err
 		}
 	}
-	return shares, nil
-}
\ No newline at end of file
+	return vanilla.SolveNormalEquations(xtx, xty, columns)
+}
+
+// decodeMoments returns the moments of the points whose encoded values have
+// the moments xtx and xty. A feature x is encoded as u = (x - o) * c, so the
+// row (1, x) of X is T (1, u), where the first column of T is 1 followed by
+// the offsets o and its diagonal is 1 followed by the 1 / c, giving
+// XᵀX = T UᵀU Tᵀ. The label y is v / c_y + o_y, giving
+// Xᵀy = T (Uᵀv / c_y + o_y Uᵀ1).
+func decodeMoments(xtx [][]float64, xty []float64,
+	encoding *vanilla.PrioSuggestion) ([][]float64, []float64, error) {
+	n := len(xty)
+	if len(encoding.Columns) != n {
+		return nil, nil, fmt.Errorf("encoding has %d columns, expected %d",
+			len(encoding.Columns), n)
+	}
+	t := make([][]float64, n)
+	for i := range t {
+		t[i] = make([]float64, n)
+	}
+	t[0][0] = 1
+	for i := 1; i < n; i++ {
+		t[i][0] = encoding.Offsets[i-1]
+		t[i][i] = 1 / encoding.Scales[i-1]
+	}
+	offset, scale := encoding.Offsets[n-1], encoding.Scales[n-1]
+	uy := make([]float64, n)
+	for i := range uy {
+		uy[i] = xty[i]/scale + offset*xtx[i][0]
+	}
+	decoded := make([][]float64, n)
+	decodedY := make([]float64, n)
+	for i := 0; i < n; i++ {
+		decoded[i] = make([]float64, n)
+		for j := 0; j < n; j++ {
+			for k := 0; k < n; k++ {
+				for l := 0; l < n; l++ {
+					decoded[i][j] += t[i][k] * xtx[k][l] * t[j][l]
+				}
+			}
+		}
+		for k := 0; k < n; k++ {
+			decodedY[i] += t[i][k] * uy[k]
+		}
+	}
+	return decoded, decodedY, nil
+}
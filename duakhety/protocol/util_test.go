@@ -0,0 +1,147 @@
+package protocol
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/henrycg/prio/config"
+	"github.com/henrycg/prio/mpc"
+	"github.com/henrycg/prio/share"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeLinReg(t *testing.T) {
+	dataset, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      50,
+		Intercept: 310,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 2, Max: 100},
+			{Name: "x2", Coefficient: -3, Max: 100},
+		},
+		Noise:   1,
+		Integer: true,
+		Seed:    2,
+	})
+	require.Nil(t, err)
+	// The sums of x1, x2, y, x1², x1 x2, x2², x1 y and x2 y, of values that
+	// are nonnegative as those aggregated by prio
+	sums := make([]int64, 8)
+	for _, p := range dataset.Points {
+		require.True(t, p.Label >= 0)
+		x1, x2 := int64(p.Variables[0]), int64(p.Variables[1])
+		y := int64(p.Label)
+		for i, v := range []int64{x1, x2, y, x1 * x1, x1 * x2, x2 * x2,
+			x1 * y, x2 * y} {
+			sums[i] += v
+		}
+	}
+	agg := &mpc.Aggregator{}
+	for _, s := range sums {
+		agg.Values = append(agg.Values, big.NewInt(s))
+	}
+	cfg := &config.Config{Fields: []config.Field{
+		{Name: "linReg0", Type: "linReg", LinRegBits: []int{8, 8, 10}}}}
+
+	model, err := DecodeLinReg(agg, cfg, len(dataset.Points), nil, nil)
+	require.Nil(t, err)
+	trainer, err := vanilla.NewTrainer("ols", nil)
+	require.Nil(t, err)
+	expected, err := trainer.Train(dataset.Points, nil)
+	require.Nil(t, err)
+	for i, c := range expected.(*vanilla.LinearModel).Coefficients {
+		require.InDelta(t, c, model.Coefficients[i], 1e-6)
+	}
+
+	// A sum that wrapped around the field is in its upper half
+	wrapped := new(big.Int).Sub(share.IntModulus, big.NewInt(3))
+	agg.Values[2], wrapped = wrapped, agg.Values[2]
+	_, err = DecodeLinReg(agg, cfg, len(dataset.Points), nil, nil)
+	require.NotNil(t, err)
+	agg.Values[2] = wrapped
+
+	cfg.Fields[0].Type = "int"
+	_, err = DecodeLinReg(agg, cfg, len(dataset.Points), nil, nil)
+	require.NotNil(t, err)
+	cfg.Fields[0].Type = "linReg"
+
+	agg.Values = agg.Values[:7]
+	_, err = DecodeLinReg(agg, cfg, len(dataset.Points), nil, nil)
+	require.NotNil(t, err)
+}
+
+func TestGetSharesRejectsFractions(t *testing.T) {
+	cfg := &config.Config{Fields: []config.Field{
+		{Name: "linReg0", Type: "linReg", LinRegBits: []int{8, 8}}}}
+	_, err := GetSharesFromDataPoint(1, []float64{0.5}, cfg, nil)
+	require.NotNil(t, err)
+	_, err = GetSharesFromDataPoint(-1, []float64{2}, cfg, nil)
+	require.NotNil(t, err)
+
+	scaler := &vanilla.Scaler{Method: vanilla.ZScore, Features: []string{"x"}}
+	err = GetSharesFromCSV("../../vanilla/tests/test2.csv", "test.conf",
+		&vanilla.LoadOptions{Scaler: scaler}, nil,
+		func([]*mpc.ClientRequest) error { return nil })
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "scaled")
+}
+
+func TestDecodeLinRegEncoded(t *testing.T) {
+	dataset, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      100,
+		Intercept: -4,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 1.5, Min: -10, Max: 10},
+			{Name: "x2", Coefficient: -0.5, Min: 0, Max: 1},
+		},
+		Noise: 0.1,
+		Seed:  3,
+	})
+	require.Nil(t, err)
+	dir, err := ioutil.TempDir("", "prio")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "linear.csv")
+	require.Nil(t, dataset.WriteCSV(fileName))
+	profile, err := vanilla.ProfileDataset(fileName, nil, 4)
+	require.Nil(t, err)
+	encoding, err := profile.SuggestPrio(dataset.Columns, 3)
+	require.Nil(t, err)
+
+	// The sums of the encoded x1, x2, y, x1², x1 x2, x2², x1 y and x2 y
+	sums := make([]*big.Int, 8)
+	for i := range sums {
+		sums[i] = new(big.Int)
+	}
+	for _, p := range dataset.Points {
+		e, err := encoding.Encode(p.Label, p.Variables)
+		require.Nil(t, err)
+		for i, v := range []int64{e[0], e[1], e[2], e[0] * e[0],
+			e[0] * e[1], e[1] * e[1], e[0] * e[2], e[1] * e[2]} {
+			sums[i].Add(sums[i], big.NewInt(v))
+		}
+	}
+	cfg := &config.Config{Fields: []config.Field{{Name: "linReg0",
+		Type: "linReg", LinRegBits: encoding.LinRegBits}}}
+	require.Nil(t, CheckLinRegBits(cfg, encoding))
+
+	model, err := DecodeLinReg(&mpc.Aggregator{Values: sums}, cfg,
+		len(dataset.Points), encoding, nil)
+	require.Nil(t, err)
+	trainer, err := vanilla.NewTrainer("ols", nil)
+	require.Nil(t, err)
+	expected, err := trainer.Train(dataset.Points, nil)
+	require.Nil(t, err)
+	for i, c := range expected.(*vanilla.LinearModel).Coefficients {
+		require.InDelta(t, c, model.Coefficients[i], 1e-2)
+	}
+
+	cfg.Fields[0].LinRegBits = []int{4, 4, 4}
+	require.NotNil(t, CheckLinRegBits(cfg, encoding))
+	_, err = GetSharesFromDataPoint(dataset.Points[0].Label,
+		dataset.Points[0].Variables, cfg, nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,364 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dedis/onet/log"
+	"github.com/sajari/regression"
+)
+
+// ErrFieldsCount is reported for rows that don't have as many fields as the
+// header
+var ErrFieldsCount = errors.New(
+	"fields count must be the same as the headers fields count")
+
+// ErrNoHeader is reported when a dataset file doesn't even have a header
+var ErrNoHeader = errors.New("dataset has no header")
+
+// ErrUnknownColumn is reported when a selected column isn't in the header
+var ErrUnknownColumn = errors.New("no such column in the header")
+
+// ErrNoFeatures is reported when the column selection leaves no feature
+var ErrNoFeatures = errors.New("no feature column selected")
+
+// LoadError describes why a dataset, one of its rows or one of its cells
+// couldn't be loaded
+type LoadError struct {
+	// File is the path of the dataset
+	File string
+	// Row is the line number in the file, the header of a csv file being
+	// row 1. It is 0 for errors concerning the whole file.
+	Row int
+	// Column is the header of the offending column, empty for errors
+	// concerning the whole row
+	Column string
+	// Err is the underlying error
+	Err error
+}
+
+func (e *LoadError) Error() string {
+	switch {
+	case e.Row == 0:
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	case e.Column == "":
+		return fmt.Sprintf("%s: row %d: %v", e.File, e.Row, e.Err)
+	default:
+		return fmt.Sprintf("%s: row %d, column %q: %v", e.File, e.Row,
+			e.Column, e.Err)
+	}
+}
+
+// LoadOptions tunes how a dataset is loaded
+type LoadOptions struct {
+	// Lenient skips the rows that can't be loaded instead of failing, they
+	// are reported in Dataset.Rejected
+	Lenient bool
+	// Label is the header of the label column, the last column if empty
+	Label string
+	// Features are the headers of the feature columns in the order they
+	// appear in the data points, all the columns but the label if empty
+	Features []string
+	// Exclude are the headers of columns that mustn't be used as features
+	Exclude []string
+	// Schema, when set, decides the label, the feature columns and how they
+	// are encoded. Label, Features and Exclude are then ignored.
+	Schema *Schema
+	// Missing tells how missing cells are handled, they are rejected if nil
+	Missing *MissingPolicy
+	// Format is the format of the dataset file, given by its extension if
+	// empty
+	Format Format
+	// Scaler, when set, scales the features of the points, which must be the
+	// features it was fitted on
+	Scaler *Scaler
+	// Unlabeled datasets have no label column, such as new points given to a
+	// trained model, and their points have a zero label. Label must then be
+	// empty, and the label of a Schema is ignored.
+	Unlabeled bool
+}
+
+// Columns names the label and the features of data points
+type Columns struct {
+	Label    string
+	Features []string
+}
+
+// NameRegression names the observed value and the variables of a regression
+// so that its formula refers to the columns by name. It must be called
+// before the regression is run.
+func (c *Columns) NameRegression(r *regression.Regression) {
+	r.SetObserved(c.Label)
+	for i, name := range c.Features {
+		r.SetVar(i, name)
+	}
+}
+
+// Dataset holds the data points loaded from a file
+type Dataset struct {
+	Columns
+	Points regression.DataPoints
+	// Rejected has one entry per row skipped in lenient mode or dropped
+	// because of missing cells
+	Rejected []*LoadError
+	// Imputed has one entry per missing cell that has been filled
+	Imputed []ImputedCell
+}
+
+// MlDataPoints returns the points of the dataset as MlDataPoints
+func (d *Dataset) MlDataPoints(desc string) []MlDataPoint {
+	points := make([]MlDataPoint, len(d.Points))
+	for i, p := range d.Points {
+		points[i] = MlDataPoint{desc, p.Observed, p.Variables}
+	}
+	return points
+}
+
+// LoadDataset loads the data points contained in a dataset file of any
+// supported format. A nil opts loads all the columns of a file whose format
+// is given by its extension in strict mode, the last column being the label.
+func LoadDataset(fileName string, opts *LoadOptions) (*Dataset, error) {
+	source, err := OpenDataSource(fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	return ReadDataset(source)
+}
+
+// ReadDataset reads all the points of a DataSource in memory
+func ReadDataset(source DataSource) (*Dataset, error) {
+	dataset := &Dataset{Columns: source.Columns()}
+	for {
+		point, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		dataset.Points = append(dataset.Points,
+			regression.DataPoint(point.Label, point.Variables))
+		dataset.Imputed = append(dataset.Imputed, source.Imputed()...)
+	}
+	dataset.Rejected = source.Rejected()
+	log.Lvlf2("Read %d instances with %d features, rejected %d rows, "+
+		"imputed %d cells", len(dataset.Points), len(dataset.Features),
+		len(dataset.Rejected), len(dataset.Imputed))
+	return dataset, nil
+}
+
+// withImputationStats returns options where the mean and median missing
+// strategies are replaced by the statistics computed on the dataset
+func (opts *LoadOptions) withImputationStats(fileName string,
+	open func(string) (recordReader, error)) (*LoadOptions, error) {
+	if opts.Missing == nil || (opts.Missing.Strategy != FillMean &&
+		opts.Missing.Strategy != FillMedian) {
+		return opts, nil
+	}
+	stats, err := computeImputationStats(fileName, opts,
+		opts.Missing.Strategy, open)
+	if err != nil {
+		return nil, err
+	}
+	missing := *opts.Missing
+	missing.Strategy = FillExternal
+	missing.Stats = stats
+	resolved := *opts
+	resolved.Missing = &missing
+	return &resolved, nil
+}
+
+// drops tells if a row is dropped, rather than rejected, because of err
+func (opts *LoadOptions) drops(err *LoadError) bool {
+	return err.Err == ErrMissingValue && opts.Missing != nil &&
+		opts.Missing.Strategy == DropMissing
+}
+
+// recordEncoder turns the records of a dataset into data points
+type recordEncoder struct {
+	file    string
+	headers []string
+	// schemas has how to encode each column of the header
+	schemas  []ColumnSchema
+	label    int
+	features []int
+	columns  Columns
+	missing  *MissingPolicy
+	// fill has the value missing cells are filled with, by column
+	fill map[int]float64
+}
+
+// newRecordEncoder selects the label and feature columns in the header of
+// a dataset
+func newRecordEncoder(fileName string, records recordReader,
+	opts *LoadOptions) (*recordEncoder, error) {
+	headers := records.Headers()
+	e := &recordEncoder{file: fileName, headers: headers,
+		schemas: make([]ColumnSchema, len(headers)), missing: opts.Missing,
+		fill: make(map[int]float64)}
+	if typed, ok := records.(typedRecords); ok {
+		copy(e.schemas, typed.Schemas())
+	} else {
+		for i, h := range headers {
+			e.schemas[i] = ColumnSchema{Name: h, Type: Numeric}
+		}
+	}
+	var err *LoadError
+	if opts.Schema != nil {
+		err = e.selectSchemaColumns(opts.Schema, opts.Unlabeled)
+	} else {
+		err = e.selectColumns(opts)
+	}
+	if err != nil {
+		err.File = fileName
+		return nil, err
+	}
+	if e.label >= 0 {
+		// A categorical label is encoded by the index of its category
+		if opts.Schema == nil && e.schemas[e.label].Type == Categorical {
+			e.schemas[e.label].Encoding = Ordinal
+		}
+		e.columns.Label = headers[e.label]
+	}
+	for _, j := range e.features {
+		e.columns.Features = append(e.columns.Features,
+			e.schemas[j].Variables()...)
+		v, ok, err := opts.Missing.fillValue(&e.schemas[j])
+		if err != nil {
+			return nil, &LoadError{File: fileName, Row: 1, Column: headers[j],
+				Err: err}
+		}
+		if ok {
+			e.fill[j] = v
+		}
+	}
+	return e, nil
+}
+
+// find returns the index of a column in the header
+func (e *recordEncoder) find(name string) (int, *LoadError) {
+	for i, h := range e.headers {
+		if h == name {
+			return i, nil
+		}
+	}
+	return -1, &LoadError{Row: 1, Column: name, Err: ErrUnknownColumn}
+}
+
+// selectColumns selects the label and the feature columns by name
+func (e *recordEncoder) selectColumns(opts *LoadOptions) *LoadError {
+	e.label = len(e.headers) - 1
+	if opts.Unlabeled {
+		if opts.Label != "" {
+			return &LoadError{Row: 1, Column: opts.Label,
+				Err: errors.New("unlabeled datasets have no label column")}
+		}
+		e.label = -1
+	} else if opts.Label != "" {
+		var err *LoadError
+		if e.label, err = e.find(opts.Label); err != nil {
+			return err
+		}
+	}
+	excluded := map[int]bool{e.label: true}
+	for _, name := range opts.Exclude {
+		i, err := e.find(name)
+		if err != nil {
+			return err
+		}
+		excluded[i] = true
+	}
+
+	if len(opts.Features) == 0 {
+		for i := range e.headers {
+			if !excluded[i] {
+				e.features = append(e.features, i)
+			}
+		}
+	} else {
+		for _, name := range opts.Features {
+			i, err := e.find(name)
+			if err != nil {
+				return err
+			}
+			if !excluded[i] {
+				e.features = append(e.features, i)
+			}
+		}
+	}
+	if len(e.features) == 0 {
+		return &LoadError{Row: 1, Err: ErrNoFeatures}
+	}
+	return nil
+}
+
+// selectSchemaColumns selects the label and the feature columns declared in
+// a schema, the label being skipped in unlabeled datasets
+func (e *recordEncoder) selectSchemaColumns(schema *Schema,
+	unlabeled bool) *LoadError {
+	if err := schema.Validate(); err != nil {
+		return &LoadError{Row: 1, Err: err}
+	}
+	e.label = -1
+	for _, c := range schema.Columns {
+		if unlabeled && c.Name == schema.Label {
+			continue
+		}
+		i, err := e.find(c.Name)
+		if err != nil {
+			return err
+		}
+		e.schemas[i] = c
+		if c.Name == schema.Label {
+			e.label = i
+		} else {
+			e.features = append(e.features, i)
+		}
+	}
+	return nil
+}
+
+// encode returns the label, the variables and the imputed cells of a record
+func (e *recordEncoder) encode(row int, record []string) (float64,
+	[]float64, []ImputedCell, error) {
+	if len(record) != len(e.headers) {
+		return 0, nil, nil, &LoadError{File: e.file, Row: row,
+			Err: ErrFieldsCount}
+	}
+	label := []float64{0}
+	if e.label >= 0 {
+		if e.missing.isMissing(record[e.label]) {
+			return 0, nil, nil, &LoadError{File: e.file, Row: row,
+				Column: e.headers[e.label], Err: ErrMissingValue}
+		}
+		var err error
+		label, err = e.schemas[e.label].Encode(record[e.label])
+		if err != nil {
+			return 0, nil, nil, &LoadError{File: e.file, Row: row,
+				Column: e.headers[e.label], Err: err}
+		}
+	}
+	variables := make([]float64, 0, len(e.columns.Features))
+	var imputed []ImputedCell
+	for _, j := range e.features {
+		if e.missing.isMissing(record[j]) {
+			v, ok := e.fill[j]
+			if !ok {
+				return 0, nil, nil, &LoadError{File: e.file, Row: row,
+					Column: e.headers[j], Err: ErrMissingValue}
+			}
+			variables = append(variables, v)
+			imputed = append(imputed, ImputedCell{row, e.headers[j], v})
+			continue
+		}
+		encoded, err := e.schemas[j].Encode(record[j])
+		if err != nil {
+			return 0, nil, nil, &LoadError{File: e.file, Row: row,
+				Column: e.headers[j], Err: err}
+		}
+		variables = append(variables, encoded...)
+	}
+	return label[0], variables, imputed, nil
+}
@@ -0,0 +1,110 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dedis/student_18_ml/vanilla/metrics"
+)
+
+// BinaryClassifier is a model predicting one of two labels. Models that are
+// binary classifiers only for some trainings, such as decision trees, tell it
+// with a Binary() bool method.
+type BinaryClassifier interface {
+	Model
+	// Probability returns the probability that a point with the given
+	// variables has the positive label
+	Probability(variables []float64) (float64, error)
+	// BinaryLabels returns the negative and positive labels
+	BinaryLabels() LabelMapping
+}
+
+// Classifier is a model predicting one of the labels it was trained on, such
+// as a classifier of more than two labels. Models that are classifiers only
+// for some trainings, such as decision trees, have no class labels otherwise.
+type Classifier interface {
+	Model
+	// ClassLabels returns the sorted labels the model predicts
+	ClassLabels() []float64
+}
+
+// Evaluation are the metrics of a model on held-out points, regression
+// metrics for regression models and classification metrics for classifiers
+type Evaluation struct {
+	Regression     *metrics.Regression     `json:",omitempty"`
+	Classification *metrics.Classification `json:",omitempty"`
+}
+
+// EvaluateModel evaluates a model on held-out points
+func EvaluateModel(m Model, points []MlDataPoint) (*Evaluation, error) {
+	if _, ok := m.(*KMeansModel); ok {
+		return nil, errors.New("clusters can't be scored against the labels")
+	}
+	actual := MlLabels(points)
+	predicted := make([]float64, len(points))
+	for i, p := range points {
+		var err error
+		predicted[i], err = m.Predict(p.Variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	classifier, ok := m.(BinaryClassifier)
+	if b, isMixed := m.(interface{ Binary() bool }); isMixed && !b.Binary() {
+		// Such as a regression tree or a classifier of three labels
+		ok = false
+	}
+	if c, isClassifier := m.(Classifier); !ok && isClassifier &&
+		len(c.ClassLabels()) > 0 {
+		mc, err := metrics.ComputeMultiClassification(actual, predicted)
+		if err != nil {
+			return nil, err
+		}
+		return &Evaluation{Classification: mc}, nil
+	}
+	if !ok {
+		r, err := metrics.ComputeRegression(actual, predicted)
+		if err != nil {
+			return nil, err
+		}
+		return &Evaluation{Regression: r}, nil
+	}
+	scores := make([]float64, len(points))
+	for i, p := range points {
+		var err error
+		scores[i], err = classifier.Probability(p.Variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	c, err := metrics.ComputeClassification(actual, predicted,
+		classifier.BinaryLabels().Positive, scores)
+	if err != nil {
+		return nil, err
+	}
+	return &Evaluation{Classification: c}, nil
+}
+
+// Save writes the evaluation as json to a file
+func (e *Evaluation) Save(fileName string) error {
+	return metrics.Save(e, fileName)
+}
+
+// String returns the main metrics of the evaluation
+func (e *Evaluation) String() string {
+	if c := e.Classification; c != nil && c.MultiClass {
+		return fmt.Sprintf("accuracy %.3f over %d labels", c.Accuracy,
+			len(c.Confusion.Labels))
+	}
+	if c := e.Classification; c != nil {
+		s := fmt.Sprintf("accuracy %.3f, precision %.3f, recall %.3f, "+
+			"F1 %.3f", c.Accuracy, c.Precision, c.Recall, c.F1)
+		if c.AUC != nil {
+			s += fmt.Sprintf(", AUC %.3f", *c.AUC)
+		}
+		return s
+	}
+	r := e.Regression
+	return fmt.Sprintf("MSE %.4g, RMSE %.4g, MAE %.4g, R2 %.3f", r.MSE,
+		r.RMSE, r.MAE, r.R2)
+}
@@ -0,0 +1,99 @@
+package vanilla_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluateModel(t *testing.T) {
+	d := linearDataset(t)
+	split, err := vanilla.TrainTestSplit(vanilla.MlLabels(d.Points), 0.2,
+		&vanilla.SplitOptions{Seed: 1})
+	require.Nil(t, err)
+	train, test := split.MlDataPoints(d.Points)
+	model, err := trainModel(t, "ols", nil, train)
+	require.Nil(t, err)
+	evaluation, err := vanilla.EvaluateModel(model, test)
+	require.Nil(t, err)
+	require.Nil(t, evaluation.Classification)
+	require.Equal(t, 60, evaluation.Regression.Count)
+	require.InDelta(t, 0.5, evaluation.Regression.RMSE, 0.1)
+	require.True(t, evaluation.Regression.R2 > 0.99)
+
+	dir, err := ioutil.TempDir("", "metrics")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "metrics.json")
+	require.Nil(t, evaluation.Save(fileName))
+	data, err := ioutil.ReadFile(fileName)
+	require.Nil(t, err)
+	loaded := &vanilla.Evaluation{}
+	require.Nil(t, json.Unmarshal(data, loaded))
+	require.Equal(t, evaluation, loaded)
+}
+
+func TestEvaluateClassifier(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:  400,
+		Model: vanilla.Logistic,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 6, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -4, Min: -1, Max: 1},
+		},
+		Seed: 3,
+	})
+	require.Nil(t, err)
+	split, err := vanilla.TrainTestSplit(vanilla.MlLabels(d.Points), 0.25,
+		&vanilla.SplitOptions{Seed: 1, Stratified: true})
+	require.Nil(t, err)
+	train, test := split.MlDataPoints(d.Points)
+	model, err := trainModel(t, "logistic", nil, train)
+	require.Nil(t, err)
+	evaluation, err := vanilla.EvaluateModel(model, test)
+	require.Nil(t, err)
+	require.Nil(t, evaluation.Regression)
+	c := evaluation.Classification
+	require.InDelta(t, 100, c.Count, 1)
+	require.Equal(t, 1.0, c.Positive)
+	require.True(t, c.Accuracy > 0.8)
+	require.True(t, *c.AUC > 0.9)
+	require.Contains(t, evaluation.String(), "AUC")
+}
+
+func TestEvaluateMultiClassifier(t *testing.T) {
+	points, _ := blobs()
+	split, err := vanilla.TrainTestSplit(vanilla.MlLabels(points), 0.2,
+		&vanilla.SplitOptions{Seed: 1, Stratified: true})
+	require.Nil(t, err)
+	train, test := split.MlDataPoints(points)
+	for _, name := range []string{"naivebayes", "tree"} {
+		params := vanilla.Params{}
+		if name == "tree" {
+			params["classification"] = "true"
+		}
+		model, err := trainModel(t, name, params, train)
+		require.Nil(t, err)
+		evaluation, err := vanilla.EvaluateModel(model, test)
+		require.Nil(t, err)
+		require.Nil(t, evaluation.Regression)
+		c := evaluation.Classification
+		require.True(t, c.MultiClass)
+		require.Nil(t, c.AUC)
+		require.Equal(t, []float64{0, 1, 2}, c.Confusion.Labels)
+		require.True(t, c.Accuracy > 0.9)
+		require.Contains(t, evaluation.String(), "over 3 labels")
+	}
+
+	// A regression tree still has regression metrics
+	model, err := trainModel(t, "tree", nil, train)
+	require.Nil(t, err)
+	evaluation, err := vanilla.EvaluateModel(model, test)
+	require.Nil(t, err)
+	require.Nil(t, evaluation.Classification)
+}
@@ -0,0 +1,115 @@
+package vanilla
+
+import (
+	"errors"
+)
+
+// GramAccumulator folds data points into the XᵀX and Xᵀy moments of a linear
+// regression, X having a leading column of ones for the intercept, sums their
+// squared labels and counts their labels. A linear model is then fitted
+// without keeping the points, which the consumer can drop as soon as they are
+// decrypted.
+type GramAccumulator struct {
+	XtX [][]float64
+	XtY []float64
+	YtY float64
+	// LabelCounts counts the points of every label
+	LabelCounts map[float64]int
+}
+
+// NewGramAccumulator returns an empty accumulator, sized by the first point
+// added
+func NewGramAccumulator() *GramAccumulator {
+	return &GramAccumulator{LabelCounts: make(map[float64]int)}
+}
+
+// Add folds a point into the moments. Every point must have the same number
+// of variables.
+func (a *GramAccumulator) Add(point *MlDataPoint) error {
+	n := len(point.Variables) + 1
+	if a.XtY == nil {
+		a.XtX = newMatrix(n, n)
+		a.XtY = make([]float64, n)
+	}
+	if len(a.XtY) != n {
+		return ErrFieldsCount
+	}
+	x := withIntercept(point.Variables)
+	for j := range x {
+		a.XtY[j] += x[j] * point.Label
+		for k := range x {
+			a.XtX[j][k] += x[j] * x[k]
+		}
+	}
+	a.YtY += point.Label * point.Label
+	a.LabelCounts[point.Label]++
+	return nil
+}
+
+// Count returns the number of points added
+func (a *GramAccumulator) Count() int {
+	if a.XtY == nil {
+		return 0
+	}
+	return int(a.XtX[0][0])
+}
+
+// Model returns the least squares linear model of the points added, as
+// trained by OLSTrainer. columns may be nil.
+func (a *GramAccumulator) Model(columns *Columns) (*LinearModel, error) {
+	return a.RidgeModel(0, columns)
+}
+
+// RidgeModel returns the linear model of the points added with an L2 penalty
+// of weight lambda, as trained by RidgeTrainer. columns may be nil.
+func (a *GramAccumulator) RidgeModel(lambda float64, columns *Columns) (
+	*LinearModel, error) {
+	if a.Count() == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	xtx := newMatrix(len(a.XtY), len(a.XtY))
+	for j := range xtx {
+		copy(xtx[j], a.XtX[j])
+		if j > 0 {
+			xtx[j][j] += lambda
+		}
+	}
+	return SolveNormalEquations(xtx, a.XtY, columns)
+}
+
+// inferredRidgeModel returns the ridge model of the points added with the
+// inference of its coefficients at confidence, unless they can't be estimated
+func (a *GramAccumulator) inferredRidgeModel(lambda float64,
+	confidence float64, columns *Columns) (*LinearModel, error) {
+	m, err := a.RidgeModel(lambda, columns)
+	if err != nil {
+		return nil, err
+	}
+	m.Inference, err = a.RidgeInference(m.Coefficients, lambda, confidence)
+	if err != nil && err != ErrNoInference {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Inference returns the inference of the least squares coefficients of the
+// points added, see InferFromMoments
+func (a *GramAccumulator) Inference(coeffs []float64, confidence float64) (
+	*CoefficientInference, error) {
+	if a.Count() == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	return InferFromMoments(a.XtX, a.XtY, a.YtY, coeffs, confidence)
+}
+
+// RidgeInference returns the inference of the coefficients of the ridge
+// regression of the points added with an L2 penalty of weight lambda, see
+// InferRidgeFromMoments
+func (a *GramAccumulator) RidgeInference(coeffs []float64, lambda float64,
+	confidence float64) (*CoefficientInference, error) {
+	if a.Count() == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	return InferRidgeFromMoments(a.XtX, a.XtY, a.YtY, coeffs, lambda,
+		confidence)
+}
@@ -0,0 +1,76 @@
+package vanilla_test
+
+import (
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGramAccumulator(t *testing.T) {
+	d := linearDataset(t)
+	a := vanilla.NewGramAccumulator()
+	_, err := a.Model(nil)
+	require.NotNil(t, err)
+	for i := range d.Points {
+		require.Nil(t, a.Add(&d.Points[i]))
+	}
+	require.Equal(t, len(d.Points), a.Count())
+	require.Equal(t, 4, len(a.XtY))
+
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	m, err := a.Model(nil)
+	require.Nil(t, err)
+	for j, c := range ols.Coefficients {
+		require.InDelta(t, c, m.Coefficients[j], 1e-6)
+	}
+	ridge, err := train(t, "ridge", vanilla.Params{"lambda": "10"}, d.Points)
+	require.Nil(t, err)
+	m, err = a.RidgeModel(10, nil)
+	require.Nil(t, err)
+	require.Equal(t, ridge.Coefficients, m.Coefficients)
+
+	require.Equal(t, vanilla.ErrFieldsCount, a.Add(&vanilla.MlDataPoint{
+		Variables: []float64{1}}))
+
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	a = vanilla.NewGramAccumulator()
+	for _, p := range dataset.MlDataPoints("test") {
+		require.Nil(t, a.Add(&p))
+	}
+	require.Equal(t, map[float64]int{1: 3, 2: 2}, a.LabelCounts)
+}
+
+func TestStreamedModel(t *testing.T) {
+	d := linearDataset(t)
+	a := vanilla.NewGramAccumulator()
+	for i := range d.Points {
+		require.Nil(t, a.Add(&d.Points[i]))
+	}
+	columns := &vanilla.Columns{Label: "y",
+		Features: []string{"x1", "x2", "x3"}}
+	s := &vanilla.MlSimulation{Streaming: true, Trainer: "ridge",
+		TrainerParams: "lambda=0"}
+	_, err := s.NewStreamingAccumulator()
+	require.Nil(t, err)
+	saved, err := s.StreamedModel(a, columns, nil)
+	require.Nil(t, err)
+	require.Equal(t, len(d.Points), saved.Metadata.TrainingPoints)
+	require.Equal(t, *columns, saved.Columns)
+	require.InDelta(t, 3, saved.Model.(*vanilla.LinearModel).Coefficients[1],
+		0.1)
+
+	// Unsupported settings are rejected before any point is accumulated
+	s.Trainer = "lasso"
+	_, err = s.NewStreamingAccumulator()
+	require.NotNil(t, err)
+	_, err = s.StreamedModel(a, columns, nil)
+	require.NotNil(t, err)
+	s.Trainer, s.TestFraction = "ols", 0.2
+	_, err = s.NewStreamingAccumulator()
+	require.NotNil(t, err)
+	_, err = s.StreamedModel(a, columns, nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,212 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// ErrMissingValue is reported for missing cells that aren't imputed
+var ErrMissingValue = errors.New("missing value")
+
+// ErrNoImputationStat is reported when external imputation statistics don't
+// cover a column
+var ErrNoImputationStat = errors.New("no imputation statistic for column")
+
+// MissingStrategy tells what to do with missing cells
+type MissingStrategy string
+
+const (
+	// FailMissing rejects the rows with missing cells, it is the default
+	FailMissing MissingStrategy = ""
+	// DropMissing drops the rows with missing cells, even in strict mode
+	DropMissing MissingStrategy = "drop"
+	// FillConstant fills missing cells with MissingPolicy.Value
+	FillConstant MissingStrategy = "constant"
+	// FillMean fills missing cells with the mean of their column
+	FillMean MissingStrategy = "mean"
+	// FillMedian fills missing cells with the median of their column
+	FillMedian MissingStrategy = "median"
+	// FillExternal fills missing cells with MissingPolicy.Stats, which
+	// providers can't compute on their own data and must be published by the
+	// consumer
+	FillExternal MissingStrategy = "external"
+)
+
+// MissingPolicy tells how missing cells are handled when loading a dataset.
+// Only numeric and boolean feature columns are imputed, rows missing a label
+// or a categorical cell are treated as with FailMissing, or dropped with
+// DropMissing.
+type MissingPolicy struct {
+	Strategy MissingStrategy
+	// Value is used by FillConstant
+	Value float64
+	// Stats are used by FillExternal
+	Stats ImputationStats
+	// Tokens are the cell values that mark a missing cell, besides the empty
+	// cell, such as "NA" or "?"
+	Tokens []string
+}
+
+// isMissing tells if a cell is missing
+func (p *MissingPolicy) isMissing(cell string) bool {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	for _, token := range p.Tokens {
+		if cell == token {
+			return true
+		}
+	}
+	return false
+}
+
+// fillValue returns the value missing cells of a column are filled with
+func (p *MissingPolicy) fillValue(c *ColumnSchema) (float64, bool, error) {
+	if p == nil || c.Type == Categorical {
+		return 0, false, nil
+	}
+	switch p.Strategy {
+	case FillConstant:
+		return p.Value, true, nil
+	case FillExternal:
+		v, ok := p.Stats[c.Name]
+		if !ok {
+			return 0, false, ErrNoImputationStat
+		}
+		return v, true, nil
+	}
+	return 0, false, nil
+}
+
+// ImputedCell records a cell that was missing and has been filled
+type ImputedCell struct {
+	// Row is the record number in the file, the header being row 1
+	Row    int
+	Column string
+	Value  float64
+}
+
+// ImputationStats are the values missing cells are filled with, by column
+type ImputationStats map[string]float64
+
+// LoadImputationStats reads imputation statistics saved by
+// ImputationStats.Save
+func LoadImputationStats(fileName string) (ImputationStats, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	stats := ImputationStats{}
+	err = json.Unmarshal(data, &stats)
+	if err != nil {
+		return nil, errors.New("couldn't decode imputation statistics: " +
+			err.Error())
+	}
+	return stats, nil
+}
+
+// Save writes the imputation statistics as json to a file, so that they can
+// be published to the data providers
+func (s ImputationStats) Save(fileName string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode imputation statistics: " +
+			err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// ComputeImputationStats computes the mean or the median, as given by the
+// strategy, of the present cells of every numeric and boolean feature column
+// of a dataset selected by opts
+func ComputeImputationStats(fileName string, opts *LoadOptions,
+	strategy MissingStrategy) (ImputationStats, error) {
+	open, err := recordOpener(fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return computeImputationStats(fileName, opts, strategy, open)
+}
+
+// computeImputationStats computes the imputation statistics of a dataset
+// file opened with open
+func computeImputationStats(fileName string, opts *LoadOptions,
+	strategy MissingStrategy, open func(string) (recordReader, error)) (
+	ImputationStats, error) {
+	if strategy != FillMean && strategy != FillMedian {
+		return nil, errors.New("imputation statistics are either mean or median")
+	}
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	records, err := open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+	headers := records.Headers()
+	encoder, err := newRecordEncoder(fileName, records, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[int][]float64)
+	for {
+		record, _, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		// Bad rows are reported when the dataset is actually loaded
+		if err != nil || len(record) != len(headers) {
+			continue
+		}
+		for _, j := range encoder.features {
+			c := &encoder.schemas[j]
+			if c.Type == Categorical || opts.Missing.isMissing(record[j]) {
+				continue
+			}
+			encoded, err := c.Encode(record[j])
+			if err != nil {
+				continue
+			}
+			values[j] = append(values[j], encoded[0])
+		}
+	}
+
+	stats := ImputationStats{}
+	for j, v := range values {
+		if strategy == FillMean {
+			stats[headers[j]] = mean(v)
+		} else {
+			stats[headers[j]] = median(v)
+		}
+	}
+	return stats, nil
+}
+
+// mean returns the mean of values
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// median returns the median of values, which it sorts
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
@@ -0,0 +1,82 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatasetMissing(t *testing.T) {
+	// Missing cells are rejected by default
+	_, err := vanilla.LoadDataset("tests/incomplete.csv", nil)
+	require.NotNil(t, err)
+	require.Equal(t, vanilla.ErrMissingValue, err.(*vanilla.LoadError).Err)
+	require.Equal(t, "field3", err.(*vanilla.LoadError).Column)
+
+	policy := &vanilla.MissingPolicy{Strategy: vanilla.DropMissing,
+		Tokens: []string{"NA"}}
+	dataset, err := vanilla.LoadDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(dataset.Points))
+	require.Equal(t, 3, len(dataset.Rejected))
+
+	policy = &vanilla.MissingPolicy{Strategy: vanilla.FillConstant,
+		Value: -1, Tokens: []string{"NA"}}
+	dataset, err = vanilla.LoadDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy, Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, 3, len(dataset.Points))
+	require.Equal(t, []float64{1, 2, -1}, dataset.Points[0].Variables)
+	require.Equal(t, []float64{3, -1, 5}, dataset.Points[1].Variables)
+	// Labels aren't imputed
+	require.Equal(t, 1, len(dataset.Rejected))
+	require.Equal(t, "label", dataset.Rejected[0].Column)
+	require.Equal(t, []vanilla.ImputedCell{{2, "field3", -1},
+		{3, "field2", -1}}, dataset.Imputed)
+}
+
+func TestLoadDatasetImpute(t *testing.T) {
+	policy := &vanilla.MissingPolicy{Strategy: vanilla.FillMean,
+		Tokens: []string{"NA"}}
+	dataset, err := vanilla.LoadDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy, Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, []float64{1, 2, 7}, dataset.Points[0].Variables)
+	require.Equal(t, []float64{3, 6, 5}, dataset.Points[1].Variables)
+
+	policy.Strategy = vanilla.FillMedian
+	stats, err := vanilla.ComputeImputationStats("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy}, vanilla.FillMedian)
+	require.Nil(t, err)
+	require.Equal(t, vanilla.ImputationStats{"field1": 4, "field2": 6,
+		"field3": 7}, stats)
+
+	// Providers get the statistics from the consumer
+	dir, err := ioutil.TempDir("", "impute")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "stats.json")
+	require.Nil(t, vanilla.ImputationStats{"field2": 0.5,
+		"field3": 1.5}.Save(fileName))
+	stats, err = vanilla.LoadImputationStats(fileName)
+	require.Nil(t, err)
+	policy = &vanilla.MissingPolicy{Strategy: vanilla.FillExternal,
+		Stats: stats, Tokens: []string{"NA"}}
+	dataset, err = vanilla.LoadDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy, Lenient: true})
+	// field1 has no statistic
+	require.NotNil(t, err)
+	require.Equal(t, vanilla.ErrNoImputationStat, err.(*vanilla.LoadError).Err)
+	dataset, err = vanilla.LoadDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy, Lenient: true,
+			Exclude: []string{"field1"}})
+	require.Nil(t, err)
+	require.Equal(t, []float64{2, 1.5}, dataset.Points[0].Variables)
+	require.Equal(t, []float64{0.5, 5}, dataset.Points[1].Variables)
+	require.Equal(t, 2, len(dataset.Imputed))
+}
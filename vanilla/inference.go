@@ -0,0 +1,302 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrNoInference is reported when the errors of coefficients can't be
+// estimated, because there are no more points than coefficients or the points
+// are fitted exactly
+var ErrNoInference = errors.New("coefficient errors can't be estimated")
+
+// DefaultConfidence is the confidence level of the coefficient intervals when
+// none is given
+const DefaultConfidence = 0.95
+
+// CoefficientInference are the uncertainty estimates of the coefficients of a
+// least squares or ridge regression, the intercept first, under the usual
+// assumption of independent normal errors of equal variance
+type CoefficientInference struct {
+	// Confidence is the confidence level of the intervals, such as 0.95
+	Confidence float64
+	// DegreesOfFreedom are the residual degrees of freedom, the number of
+	// points minus the effective number of coefficients
+	DegreesOfFreedom float64
+	// ResidualVariance is the estimated variance of the errors
+	ResidualVariance float64
+	// Covariance is the estimated covariance matrix of the coefficients
+	Covariance [][]float64
+	StdErrors  []float64
+	TValues    []float64
+	// PValues are the two-sided p-values of the coefficients being 0
+	PValues []float64
+	// Lower and Upper bound the confidence intervals of the coefficients
+	Lower []float64
+	Upper []float64
+}
+
+// InferFromMoments returns the inference of the least squares coefficients
+// of points summed up by their XᵀX and Xᵀy moments, X having a leading column
+// of ones, and the sum yᵀy of their squared labels. confidence is
+// DefaultConfidence if 0. It returns ErrNoInference if the errors can't be
+// estimated.
+func InferFromMoments(xtx [][]float64, xty []float64, yty float64,
+	coeffs []float64, confidence float64) (*CoefficientInference, error) {
+	return InferRidgeFromMoments(xtx, xty, yty, coeffs, 0, confidence)
+}
+
+// InferRidgeFromMoments returns the inference of the coefficients of a ridge
+// regression with an L2 penalty of weight lambda, see InferFromMoments. Their
+// covariance is the sandwich σ² (XᵀX + λI)⁻¹ XᵀX (XᵀX + λI)⁻¹, the intercept
+// being unpenalized, which is σ² (XᵀX)⁻¹ without penalty, and σ² has n -
+// tr(H) degrees of freedom, H = X (XᵀX + λI)⁻¹ Xᵀ being the hat matrix. The
+// intervals are centered on the shrunk coefficients, so they are biased
+// towards 0 as much as the coefficients.
+func InferRidgeFromMoments(xtx [][]float64, xty []float64, yty float64,
+	coeffs []float64, lambda float64, confidence float64) (
+	*CoefficientInference, error) {
+	n := len(coeffs)
+	if len(xty) != n || len(xtx) != n {
+		return nil, ErrFieldsCount
+	}
+	penalized := newMatrix(n, n)
+	for j := range penalized {
+		copy(penalized[j], xtx[j])
+		if j > 0 {
+			penalized[j][j] += lambda
+		}
+	}
+	inverse, err := invert(penalized)
+	if err != nil {
+		return nil, err
+	}
+	// tr(H) = tr((XᵀX + λI)⁻¹ XᵀX), XᵀX being symmetric, which is the number
+	// of coefficients without penalty
+	dof := xtx[0][0] - float64(n)
+	if lambda > 0 {
+		dof = xtx[0][0]
+		for j := range inverse {
+			dof -= dot(inverse[j], xtx[j])
+		}
+	}
+	if dof <= 0 {
+		return nil, ErrNoInference
+	}
+	// The residual sum of squares is yᵀy - 2 βᵀXᵀy + βᵀXᵀXβ
+	rss := yty - 2*dot(coeffs, xty)
+	for j := range xtx {
+		rss += coeffs[j] * dot(xtx[j], coeffs)
+	}
+	sigma2 := math.Max(rss, 0) / dof
+	covariance := newMatrix(n, n)
+	for j := range covariance {
+		for k := range covariance[j] {
+			for l := range xtx {
+				for m := range xtx[l] {
+					covariance[j][k] += inverse[j][l] * xtx[l][m] *
+						inverse[m][k]
+				}
+			}
+			covariance[j][k] *= sigma2
+		}
+	}
+	return newCoefficientInference(coeffs, covariance, dof, sigma2,
+		confidence)
+}
+
+// InferFromPoints returns the inference of the least squares coefficients of
+// points, see InferFromMoments
+func InferFromPoints(points []MlDataPoint, coeffs []float64,
+	confidence float64) (*CoefficientInference, error) {
+	a := NewGramAccumulator()
+	for i := range points {
+		if err := a.Add(&points[i]); err != nil {
+			return nil, err
+		}
+	}
+	return a.Inference(coeffs, confidence)
+}
+
+// newCoefficientInference derives the errors, the t statistics, the p-values
+// and the intervals of coefficients from their covariance
+func newCoefficientInference(coeffs []float64, covariance [][]float64,
+	dof float64, sigma2 float64, confidence float64) (*CoefficientInference,
+	error) {
+	if confidence == 0 {
+		confidence = DefaultConfidence
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, errors.New("confidence must be between 0 and 1")
+	}
+	n := len(coeffs)
+	c := &CoefficientInference{Confidence: confidence,
+		DegreesOfFreedom: dof, ResidualVariance: sigma2,
+		Covariance: covariance, StdErrors: make([]float64, n),
+		TValues: make([]float64, n), PValues: make([]float64, n),
+		Lower: make([]float64, n), Upper: make([]float64, n)}
+	q := studentTQuantile(1-(1-confidence)/2, dof)
+	for j, b := range coeffs {
+		c.StdErrors[j] = math.Sqrt(math.Max(covariance[j][j], 0))
+		if c.StdErrors[j] == 0 {
+			return nil, ErrNoInference
+		}
+		c.TValues[j] = b / c.StdErrors[j]
+		c.PValues[j] = studentTPValue(c.TValues[j], dof)
+		c.Lower[j] = b - q*c.StdErrors[j]
+		c.Upper[j] = b + q*c.StdErrors[j]
+	}
+	return c, nil
+}
+
+// transform returns the inference of the coefficients t coeffs, a linear
+// transformation of the coefficients of c
+func (c *CoefficientInference) transform(t [][]float64, coeffs []float64) (
+	*CoefficientInference, error) {
+	n := len(coeffs)
+	covariance := newMatrix(n, n)
+	for i := range covariance {
+		for j := range covariance[i] {
+			for k := range c.Covariance {
+				for l := range c.Covariance[k] {
+					covariance[i][j] += t[i][k] * c.Covariance[k][l] * t[j][l]
+				}
+			}
+		}
+	}
+	return newCoefficientInference(coeffs, covariance, c.DegreesOfFreedom,
+		c.ResidualVariance, c.Confidence)
+}
+
+// Table formats the inference of coefficients as a table, naming the
+// features by their index if names is empty
+func (c *CoefficientInference) Table(names []string, coeffs []float64) string {
+	level := fmt.Sprintf("%g%%", 100*c.Confidence)
+	rows := []string{fmt.Sprintf("%-16s %12s %12s %8s %10s  %s", "",
+		"Estimate", "Std. Error", "t value", "Pr(>|t|)", level+" interval")}
+	for j, b := range coeffs {
+		name := "(Intercept)"
+		if j > 0 {
+			name = fmt.Sprintf("X%d", j-1)
+			if j-1 < len(names) {
+				name = names[j-1]
+			}
+		}
+		rows = append(rows, fmt.Sprintf("%-16s %12.4g %12.4g %8.3f %10.4g  "+
+			"[%.4g, %.4g]", name, b, c.StdErrors[j], c.TValues[j],
+			c.PValues[j], c.Lower[j], c.Upper[j]))
+	}
+	rows = append(rows, fmt.Sprintf("Residual standard error: %.4g on %.4g "+
+		"degrees of freedom", math.Sqrt(c.ResidualVariance),
+		c.DegreesOfFreedom))
+	return strings.Join(rows, "\n")
+}
+
+// invert returns the inverse of a square matrix, which is left untouched
+func invert(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	m := newMatrix(n, n)
+	for i := range a {
+		copy(m[i], a[i])
+	}
+	f, err := factorLU(m)
+	if err != nil {
+		return nil, err
+	}
+	inverse := newMatrix(n, n)
+	e := make([]float64, n)
+	for k := 0; k < n; k++ {
+		e[k] = 1
+		for i, v := range f.solve(e) {
+			inverse[i][k] = v
+		}
+		e[k] = 0
+	}
+	return inverse, nil
+}
+
+// studentTPValue returns the two-sided p-value of a t statistic with dof
+// degrees of freedom
+func studentTPValue(t float64, dof float64) float64 {
+	if math.IsNaN(t) {
+		return math.NaN()
+	}
+	if math.IsInf(t, 0) {
+		return 0
+	}
+	return incompleteBeta(dof/(dof+t*t), dof/2, 0.5)
+}
+
+// studentTQuantile returns the p quantile, p > 0.5, of the Student t
+// distribution with dof degrees of freedom, by bisection
+func studentTQuantile(p float64, dof float64) float64 {
+	target := 2 * (1 - p)
+	low, high := 0.0, 1.0
+	for studentTPValue(high, dof) > target {
+		low, high = high, 2*high
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if studentTPValue(mid, dof) > target {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// incompleteBeta returns the regularized incomplete beta function I_x(a, b)
+func incompleteBeta(x float64, a float64, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly below this bound, the
+	// symmetry I_x(a, b) = 1 - I_1-x(b, a) is used above it
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta
+// function by the modified Lentz method
+func betaFraction(x float64, a float64, b float64) float64 {
+	const tiny = 1e-300
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		for _, numerator := range []float64{
+			m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m)),
+			-(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1)),
+		} {
+			d = 1 + numerator*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + numerator/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			h *= d * c
+		}
+		if math.Abs(d*c-1) < 1e-15 {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,146 @@
+package vanilla_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferFromPoints(t *testing.T) {
+	// A simple regression of 12 points has 10 degrees of freedom
+	xs := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}
+	noise := []float64{0.3, -0.5, 0.1, 0.8, -0.2, -0.9, 0.4, 0.2, -0.6, 0.5,
+		-0.1, 0.0}
+	points := make([]vanilla.MlDataPoint, len(xs))
+	for i, x := range xs {
+		points[i] = vanilla.MlDataPoint{Label: 1 + 0.5*x + noise[i],
+			Variables: []float64{x}}
+	}
+	model, err := trainModel(t, "ols", nil, points)
+	require.Nil(t, err)
+	m := model.(*vanilla.LinearModel)
+	c := m.Inference
+	require.NotNil(t, c)
+	require.Equal(t, 10.0, c.DegreesOfFreedom)
+
+	// The error of the slope is sqrt(σ² / Σ(x - x̄)²)
+	rss, sxx := 0.0, 0.0
+	for _, p := range points {
+		predicted, err := m.Predict(p.Variables)
+		require.Nil(t, err)
+		rss += (p.Label - predicted) * (p.Label - predicted)
+		sxx += (p.Variables[0] - 6.5) * (p.Variables[0] - 6.5)
+	}
+	require.InDelta(t, rss/10, c.ResidualVariance, 1e-9)
+	require.InDelta(t, math.Sqrt(rss/10/sxx), c.StdErrors[1], 1e-9)
+	require.InDelta(t, m.Coefficients[1]/c.StdErrors[1], c.TValues[1], 1e-9)
+	require.True(t, c.PValues[1] < 1e-6)
+	// The 97.5% quantile of the t distribution of 10 degrees of freedom is
+	// 2.228139
+	require.InDelta(t, 2.228139, (c.Upper[1]-c.Lower[1])/(2*c.StdErrors[1]),
+		1e-5)
+	require.True(t, c.Lower[1] < 0.5 && 0.5 < c.Upper[1])
+	require.Contains(t, m.Summary(), "(Intercept)")
+
+	// The sufficient statistics give the same inference
+	a := vanilla.NewGramAccumulator()
+	for i := range points {
+		require.Nil(t, a.Add(&points[i]))
+	}
+	fromMoments, err := vanilla.InferFromMoments(a.XtX, a.XtY, a.YtY,
+		m.Coefficients, 0)
+	require.Nil(t, err)
+	for j := range m.Coefficients {
+		require.InDelta(t, c.StdErrors[j], fromMoments.StdErrors[j], 1e-9)
+		require.InDelta(t, c.PValues[j], fromMoments.PValues[j], 1e-9)
+	}
+
+	// 90% intervals are bounded by the 95% quantile, 1.812461
+	c, err = vanilla.InferFromPoints(points, []float64{m.Coefficients[0],
+		m.Coefficients[1]}, 0.9)
+	require.Nil(t, err)
+	require.InDelta(t, 1.812461, (c.Upper[1]-c.Lower[1])/(2*c.StdErrors[1]),
+		1e-5)
+
+	// Points on a line are fitted exactly
+	_, err = vanilla.InferFromPoints(points[:2], m.Coefficients, 0)
+	require.Equal(t, vanilla.ErrNoInference, err)
+	_, err = vanilla.NewTrainer("ols", vanilla.Params{"confidence": "95"})
+	require.NotNil(t, err)
+}
+
+func TestUnscaledInference(t *testing.T) {
+	d := linearDataset(t)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+
+	scaled := make([]vanilla.MlDataPoint, len(d.Points))
+	for i, p := range d.Points {
+		scaled[i] = vanilla.MlDataPoint{Label: p.Label,
+			Variables: append([]float64{}, p.Variables...)}
+	}
+	scaler, err := vanilla.FitScaler(vanilla.ZScore, d.Features, scaled)
+	require.Nil(t, err)
+	require.Nil(t, scaler.ScalePoints(scaled))
+	model, err := trainModel(t, "ols", nil, scaled)
+	require.Nil(t, err)
+	unscaled, err := scaler.UnscaleModel(model)
+	require.Nil(t, err)
+	c := unscaled.(*vanilla.LinearModel).Inference
+	for j := range ols.Coefficients {
+		require.InDelta(t, ols.Inference.StdErrors[j], c.StdErrors[j], 1e-6)
+		require.InDelta(t, ols.Inference.Lower[j], c.Lower[j], 1e-6)
+	}
+	// The p-value of the slopes doesn't depend on the scale
+	require.InDelta(t, ols.Inference.PValues[3], c.PValues[3], 1e-9)
+}
+
+func TestRidgeInference(t *testing.T) {
+	d := linearDataset(t)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	// Without penalty, the ridge inference is the least squares one
+	ridge, err := train(t, "ridge", vanilla.Params{"lambda": "0"}, d.Points)
+	require.Nil(t, err)
+	require.NotNil(t, ridge.Inference)
+	for j := range ols.Coefficients {
+		require.InDelta(t, ols.Inference.StdErrors[j],
+			ridge.Inference.StdErrors[j], 1e-9)
+	}
+
+	// The variance of the slope of a simple regression is
+	// σ² Sxx / (Sxx + λ)², Sxx = Σ(x - x̄)²
+	points := make([]vanilla.MlDataPoint, len(d.Points))
+	for i, p := range d.Points {
+		points[i] = vanilla.MlDataPoint{Label: p.Label,
+			Variables: p.Variables[:1]}
+	}
+	ridge, err = train(t, "ridge", vanilla.Params{"lambda": "500",
+		"confidence": "0.9"}, points)
+	require.Nil(t, err)
+	c := ridge.Inference
+	require.NotNil(t, c)
+	require.Equal(t, 0.9, c.Confidence)
+	mean, sxx, rss := 0.0, 0.0, 0.0
+	for _, p := range points {
+		mean += p.Variables[0] / float64(len(points))
+	}
+	for _, p := range points {
+		predicted, err := ridge.Predict(p.Variables)
+		require.Nil(t, err)
+		rss += (p.Label - predicted) * (p.Label - predicted)
+		sxx += (p.Variables[0] - mean) * (p.Variables[0] - mean)
+	}
+	// The effective number of coefficients is tr(H) = 1 + Sxx / (Sxx + λ)
+	dof := float64(len(points)) - 1 - sxx/(sxx+500)
+	require.InDelta(t, dof, c.DegreesOfFreedom, 1e-9)
+	require.True(t, c.DegreesOfFreedom > float64(len(points)-2))
+	require.InDelta(t, rss/dof, c.ResidualVariance, 1e-9)
+	require.InDelta(t, math.Sqrt(c.ResidualVariance*sxx)/(sxx+500),
+		c.StdErrors[1], 1e-9)
+
+	_, err = vanilla.NewTrainer("ridge", vanilla.Params{"confidence": "1"})
+	require.NotNil(t, err)
+}
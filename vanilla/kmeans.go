@@ -0,0 +1,311 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+)
+
+func init() {
+	RegisterTrainer("kmeans", newKMeansTrainer, decodeKMeansModel)
+}
+
+// KMeansOptions configure a k-means clustering
+type KMeansOptions struct {
+	// K is the number of clusters, 2 if the options are nil
+	K int
+	// Seed seeds the k-means++ initialization
+	Seed int64
+	// MaxIterations bounds the iterations, 100 if 0
+	MaxIterations int
+	// Tolerance stops the iterations once no centroid moves by more, 1e-6 if
+	// 0
+	Tolerance float64
+}
+
+// KMeansModel is a clustering of points around centroids. It predicts the
+// index of the closest centroid, labels being ignored.
+type KMeansModel struct {
+	Columns
+	Centroids  [][]float64
+	Iterations int
+	Converged  bool
+	// Inertia is the sum of the squared distances of the points to their
+	// centroid at the last iteration
+	Inertia float64
+}
+
+// ClusterSums are the sums of the points of every cluster, from which the
+// centroids of the next iteration of k-means are computed. Merge adds up the
+// sums that several providers computed with the same centroids.
+type ClusterSums struct {
+	Counts []int
+	Sums   [][]float64
+	// Inertia is the sum of the squared distances of the points to their
+	// centroid
+	Inertia float64
+}
+
+// ComputeClusterSums assigns points to their closest centroid and sums them
+func ComputeClusterSums(centroids [][]float64, points []MlDataPoint) (
+	*ClusterSums, error) {
+	if len(centroids) == 0 {
+		return nil, errors.New("no centroids")
+	}
+	d := len(centroids[0])
+	s := &ClusterSums{Counts: make([]int, len(centroids)),
+		Sums: newMatrix(len(centroids), d)}
+	for _, p := range points {
+		if len(p.Variables) != d {
+			return nil, ErrFieldsCount
+		}
+		c, distance := closestCentroid(centroids, p.Variables)
+		s.Counts[c]++
+		s.Inertia += distance
+		for j, v := range p.Variables {
+			s.Sums[c][j] += v
+		}
+	}
+	return s, nil
+}
+
+// Merge adds the sums of other points to s
+func (s *ClusterSums) Merge(other *ClusterSums) error {
+	if len(other.Counts) != len(s.Counts) {
+		return errors.New("sums have different numbers of clusters")
+	}
+	for c := range s.Counts {
+		if len(other.Sums[c]) != len(s.Sums[c]) {
+			return ErrFieldsCount
+		}
+		s.Counts[c] += other.Counts[c]
+		for j := range s.Sums[c] {
+			s.Sums[c][j] += other.Sums[c][j]
+		}
+	}
+	s.Inertia += other.Inertia
+	return nil
+}
+
+// closestCentroid returns the index of the centroid closest to a point and
+// their squared distance
+func closestCentroid(centroids [][]float64, x []float64) (int, float64) {
+	best, bestDistance := 0, math.Inf(1)
+	for c, centroid := range centroids {
+		if distance := squaredDistance(centroid, x); distance < bestDistance {
+			best, bestDistance = c, distance
+		}
+	}
+	return best, bestDistance
+}
+
+// squaredDistance returns the squared euclidean distance of two points
+func squaredDistance(x []float64, y []float64) float64 {
+	sum := 0.0
+	for j := range x {
+		sum += (x[j] - y[j]) * (x[j] - y[j])
+	}
+	return sum
+}
+
+// KMeansPlusPlus chooses k initial centroids among points, every centroid
+// being chosen with a probability proportional to its squared distance to the
+// closest centroid already chosen
+func KMeansPlusPlus(points []MlDataPoint, k int, seed int64) ([][]float64,
+	error) {
+	if k < 1 || k > len(points) {
+		return nil, errors.New("k must be between 1 and the number of points")
+	}
+	r := rand.New(rand.NewSource(seed))
+	copyPoint := func(i int) []float64 {
+		return append([]float64{}, points[i].Variables...)
+	}
+	centroids := [][]float64{copyPoint(r.Intn(len(points)))}
+	distances := make([]float64, len(points))
+	for len(centroids) < k {
+		total := 0.0
+		for i, p := range points {
+			if len(p.Variables) != len(centroids[0]) {
+				return nil, ErrFieldsCount
+			}
+			_, distances[i] = closestCentroid(centroids, p.Variables)
+			total += distances[i]
+		}
+		if total == 0 {
+			// Every point is a centroid already
+			return nil, errors.New("fewer distinct points than clusters")
+		}
+		target, chosen := r.Float64()*total, len(points)-1
+		for i, distance := range distances {
+			if target -= distance; target < 0 {
+				chosen = i
+				break
+			}
+		}
+		centroids = append(centroids, copyPoint(chosen))
+	}
+	return centroids, nil
+}
+
+// TrainKMeans clusters points, initialized by KMeansPlusPlus. columns and opts
+// may be nil.
+func TrainKMeans(points []MlDataPoint, columns *Columns,
+	opts *KMeansOptions) (*KMeansModel, error) {
+	if opts == nil {
+		opts = &KMeansOptions{K: 2}
+	}
+	centroids, err := KMeansPlusPlus(points, opts.K, opts.Seed)
+	if err != nil {
+		return nil, err
+	}
+	return TrainKMeansFromSums(centroids, columns, opts,
+		func(centroids [][]float64) (*ClusterSums, error) {
+			return ComputeClusterSums(centroids, points)
+		})
+}
+
+// TrainKMeansFromSums clusters points starting from the given centroids. Every
+// iteration only needs the sums of the clusters of the current centroids,
+// returned by sums, such as the merged ClusterSums of providers. opts.K and
+// opts.Seed are ignored and columns and opts may be nil.
+func TrainKMeansFromSums(centroids [][]float64, columns *Columns,
+	opts *KMeansOptions, sums func([][]float64) (*ClusterSums, error)) (
+	*KMeansModel, error) {
+	if opts == nil {
+		opts = &KMeansOptions{}
+	}
+	maxIterations, tolerance := opts.MaxIterations, opts.Tolerance
+	if maxIterations == 0 {
+		maxIterations = 100
+	}
+	if tolerance == 0 {
+		tolerance = 1e-6
+	}
+	m := &KMeansModel{}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	// The centroids of the caller are left untouched
+	initial := centroids
+	centroids = make([][]float64, len(initial))
+	for c := range initial {
+		centroids[c] = append([]float64{}, initial[c]...)
+	}
+	for m.Iterations < maxIterations && !m.Converged {
+		m.Iterations++
+		s, err := sums(centroids)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Counts) != len(centroids) {
+			return nil, errors.New("sums don't match the centroids")
+		}
+		m.Inertia = s.Inertia
+		m.Converged = true
+		for c, centroid := range centroids {
+			// A cluster without points keeps its centroid
+			if s.Counts[c] == 0 {
+				continue
+			}
+			for j := range centroid {
+				updated := s.Sums[c][j] / float64(s.Counts[c])
+				if math.Abs(updated-centroid[j]) > tolerance {
+					m.Converged = false
+				}
+				centroid[j] = updated
+			}
+		}
+	}
+	m.Centroids = centroids
+	return m, nil
+}
+
+// Predict implements Model, it returns the index of the closest centroid
+func (m *KMeansModel) Predict(variables []float64) (float64, error) {
+	if len(m.Centroids) == 0 || len(variables) != len(m.Centroids[0]) {
+		return 0, ErrFieldsCount
+	}
+	c, _ := closestCentroid(m.Centroids, variables)
+	return float64(c), nil
+}
+
+// Describe implements Model, it returns the centroids
+func (m *KMeansModel) Describe() string {
+	clusters := make([]string, len(m.Centroids))
+	for c, centroid := range m.Centroids {
+		coordinates := make([]string, len(centroid))
+		for j, v := range centroid {
+			name := fmt.Sprintf("X%d", j)
+			if j < len(m.Features) {
+				name = m.Features[j]
+			}
+			coordinates[j] = fmt.Sprintf("%v=%.2f", name, v)
+		}
+		clusters[c] = fmt.Sprintf("%d: %s", c, strings.Join(coordinates, ", "))
+	}
+	return fmt.Sprintf("K-means inertia %.2f, centroids ", m.Inertia) +
+		strings.Join(clusters, "; ")
+}
+
+// MarshalBinary implements Model
+func (m *KMeansModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeKMeansModel(data []byte) (Model, error) {
+	m := &KMeansModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode k-means model: " + err.Error())
+	}
+	return m, nil
+}
+
+// KMeansTrainer clusters points with TrainKMeans. Its parameters are the
+// KMeansOptions k, seed, maxIterations and tolerance.
+type KMeansTrainer struct {
+	Options KMeansOptions
+}
+
+func newKMeansTrainer(params Params) (Trainer, error) {
+	err := params.Check("k", "seed", "maxIterations", "tolerance")
+	if err != nil {
+		return nil, err
+	}
+	t := &KMeansTrainer{}
+	var seed int
+	t.Options.K, err = params.Int("k", 2)
+	if err == nil {
+		seed, err = params.Int("seed", 0)
+	}
+	if err == nil {
+		t.Options.MaxIterations, err = params.Int("maxIterations", 0)
+	}
+	if err == nil {
+		t.Options.Tolerance, err = params.Float("tolerance", 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Options.MaxIterations < 0 || t.Options.Tolerance < 0 {
+		return nil, errors.New("maxIterations and tolerance can't be " +
+			"negative")
+	}
+	t.Options.Seed = int64(seed)
+	return t, nil
+}
+
+// Train implements Trainer, it fails if the clustering doesn't converge
+func (t *KMeansTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	m, err := TrainKMeans(points, columns, &t.Options)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Converged {
+		return nil, errors.New("k-means didn't converge")
+	}
+	return m, nil
+}
@@ -0,0 +1,133 @@
+package vanilla_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+// blobs returns points drawn around three centers
+func blobs() ([]vanilla.MlDataPoint, [][]float64) {
+	centers := [][]float64{{0, 0}, {10, 0}, {0, 10}}
+	r := rand.New(rand.NewSource(8))
+	var points []vanilla.MlDataPoint
+	for i := 0; i < 300; i++ {
+		c := centers[i%3]
+		points = append(points, vanilla.MlDataPoint{Label: float64(i % 3),
+			Variables: []float64{c[0] + r.NormFloat64(),
+				c[1] + r.NormFloat64()}})
+	}
+	return points, centers
+}
+
+func TestTrainKMeans(t *testing.T) {
+	points, centers := blobs()
+	opts := &vanilla.KMeansOptions{K: 3, Seed: 1}
+	m, err := vanilla.TrainKMeans(points, nil, opts)
+	require.Nil(t, err)
+	require.True(t, m.Converged)
+	// Every center has a centroid
+	for _, center := range centers {
+		c, err := m.Predict(center)
+		require.Nil(t, err)
+		require.InDelta(t, center[0], m.Centroids[int(c)][0], 0.5)
+		require.InDelta(t, center[1], m.Centroids[int(c)][1], 0.5)
+	}
+	// The points of a blob share their cluster
+	for i := 3; i < len(points); i++ {
+		expected, err := m.Predict(points[i%3].Variables)
+		require.Nil(t, err)
+		c, err := m.Predict(points[i].Variables)
+		require.Nil(t, err)
+		require.Equal(t, expected, c)
+	}
+	require.InDelta(t, 600, m.Inertia, 100)
+
+	// The seed makes the clustering reproducible
+	again, err := vanilla.TrainKMeans(points, nil, opts)
+	require.Nil(t, err)
+	require.Equal(t, m, again)
+
+	_, err = vanilla.TrainKMeans(points[:2], nil, opts)
+	require.NotNil(t, err)
+	// Without options, the points are clustered in 2
+	m, err = vanilla.TrainKMeans(points, nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(m.Centroids))
+	_, err = m.Predict([]float64{1})
+	require.Equal(t, vanilla.ErrFieldsCount, err)
+}
+
+func TestTrainKMeansFromSums(t *testing.T) {
+	points, _ := blobs()
+	opts := &vanilla.KMeansOptions{K: 3, Seed: 1}
+	expected, err := vanilla.TrainKMeans(points, nil, opts)
+	require.Nil(t, err)
+
+	// Two providers only reveal the sums of their points
+	centroids, err := vanilla.KMeansPlusPlus(points, 3, 1)
+	require.Nil(t, err)
+	m, err := vanilla.TrainKMeansFromSums(centroids, nil, opts,
+		func(centroids [][]float64) (*vanilla.ClusterSums, error) {
+			sums, err := vanilla.ComputeClusterSums(centroids, points[:100])
+			if err != nil {
+				return nil, err
+			}
+			other, err := vanilla.ComputeClusterSums(centroids, points[100:])
+			if err != nil {
+				return nil, err
+			}
+			return sums, sums.Merge(other)
+		})
+	require.Nil(t, err)
+	require.Equal(t, expected.Iterations, m.Iterations)
+	for c := range m.Centroids {
+		for j := range m.Centroids[c] {
+			require.InDelta(t, expected.Centroids[c][j], m.Centroids[c][j],
+				1e-9)
+		}
+	}
+	require.InDelta(t, expected.Inertia, m.Inertia, 1e-6)
+	m, err = vanilla.TrainKMeansFromSums(centroids, nil, nil,
+		func(centroids [][]float64) (*vanilla.ClusterSums, error) {
+			return vanilla.ComputeClusterSums(centroids, points)
+		})
+	require.Nil(t, err)
+	require.Equal(t, expected.Centroids, m.Centroids)
+
+	trained, err := trainModel(t, "kmeans", vanilla.Params{"k": "3",
+		"seed": "1"}, points)
+	require.Nil(t, err)
+	data, err := trained.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("kmeans", data)
+	require.Nil(t, err)
+	require.Equal(t, trained, decoded)
+	require.Contains(t, decoded.Describe(), "K-means")
+	_, err = vanilla.NewTrainer("kmeans",
+		vanilla.Params{"maxIterations": "-1"})
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("kmeans", vanilla.Params{"tolerance": "-1"})
+	require.NotNil(t, err)
+}
+
+func TestKMeansSimulation(t *testing.T) {
+	points, _ := blobs()
+	s := &vanilla.MlSimulation{Trainer: "kmeans", TrainerParams: "k=3"}
+	saved, err := s.TrainModel(points, nil, nil)
+	require.Nil(t, err)
+	require.Nil(t, saved.Metadata.Evaluation)
+
+	// Cluster indices aren't predictions of the labels
+	s.TestFraction = 0.2
+	_, err = s.TrainModel(points, nil, nil)
+	require.NotNil(t, err)
+	s.TestFraction = 0
+	s.SearchSpace = "k=2|3"
+	_, err = s.TrainModel(points, nil, nil)
+	require.NotNil(t, err)
+	_, err = vanilla.EvaluateModel(saved.Model, points)
+	require.NotNil(t, err)
+}
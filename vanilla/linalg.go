@@ -0,0 +1,116 @@
+package vanilla
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrSingular is reported when a linear system has no unique solution
+var ErrSingular = errors.New("singular matrix")
+
+// solve solves a x = b by gaussian elimination with partial pivoting. a is
+// overwritten.
+func solve(a [][]float64, b []float64) ([]float64, error) {
+	f, err := factorLU(a)
+	if err != nil {
+		return nil, err
+	}
+	return f.solve(b), nil
+}
+
+// luFactors are the LU factors of a square matrix with partial pivoting, the
+// multipliers of L below the diagonal of lu and U above it
+type luFactors struct {
+	lu [][]float64
+	// rows are the rows of the matrix in their pivoted order
+	rows []int
+}
+
+// factorLU factors a square matrix, which is overwritten. It is singular if a
+// pivot isn't larger than the rounding errors of its elimination,
+// n ε max|a_ij|.
+func factorLU(a [][]float64) (*luFactors, error) {
+	n := len(a)
+	tolerance := float64(n) * epsilon * maxAbs(a)
+	f := &luFactors{lu: a, rows: make([]int, n)}
+	for i := range f.rows {
+		f.rows[i] = i
+	}
+	for k := 0; k < n; k++ {
+		pivot := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(a[i][k]) > math.Abs(a[pivot][k]) {
+				pivot = i
+			}
+		}
+		if math.Abs(a[pivot][k]) <= tolerance {
+			return nil, ErrSingular
+		}
+		a[k], a[pivot] = a[pivot], a[k]
+		f.rows[k], f.rows[pivot] = f.rows[pivot], f.rows[k]
+		for i := k + 1; i < n; i++ {
+			a[i][k] /= a[k][k]
+			for j := k + 1; j < n; j++ {
+				a[i][j] -= a[i][k] * a[k][j]
+			}
+		}
+	}
+	return f, nil
+}
+
+// solve returns the solution x of a x = b by substitution, b being left
+// untouched
+func (f *luFactors) solve(b []float64) []float64 {
+	n := len(f.rows)
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		x[i] = b[f.rows[i]]
+		for j := 0; j < i; j++ {
+			x[i] -= f.lu[i][j] * x[j]
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := i + 1; j < n; j++ {
+			x[i] -= f.lu[i][j] * x[j]
+		}
+		x[i] /= f.lu[i][i]
+	}
+	return x
+}
+
+// epsilon is the spacing of the float64 values around 1
+const epsilon = 2.220446049250313e-16
+
+// maxAbs returns the largest absolute value of the entries of a matrix
+func maxAbs(a [][]float64) float64 {
+	m := 0.0
+	for i := range a {
+		for _, v := range a[i] {
+			m = math.Max(m, math.Abs(v))
+		}
+	}
+	return m
+}
+
+// newMatrix returns a zero rows x cols matrix
+func newMatrix(rows int, cols int) [][]float64 {
+	m := make([][]float64, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+// withIntercept returns the variables of a point preceded by a 1
+func withIntercept(variables []float64) []float64 {
+	return append([]float64{1}, variables...)
+}
+
+// dot returns the dot product of two vectors of the same length
+func dot(x []float64, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
@@ -0,0 +1,287 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+func init() {
+	RegisterTrainer("ols", newOLSTrainer, decodeLinearModel)
+	RegisterTrainer("ridge", newRidgeTrainer, decodeLinearModel)
+	RegisterTrainer("lasso", newLassoTrainer, decodeLinearModel)
+}
+
+// LinearModel is a linear regression model
+type LinearModel struct {
+	Columns
+	// Coefficients are the intercept followed by a coefficient per feature
+	Coefficients []float64
+	// Inference, if set, are the uncertainty estimates of the coefficients
+	Inference *CoefficientInference `json:",omitempty"`
+}
+
+// Predict implements Model
+func (m *LinearModel) Predict(variables []float64) (float64, error) {
+	if len(variables) != len(m.Coefficients)-1 {
+		return 0, ErrFieldsCount
+	}
+	return dot(withIntercept(variables), m.Coefficients), nil
+}
+
+// Describe implements Model, it returns the formula of the model
+func (m *LinearModel) Describe() string {
+	return linearFormula("Predicted", m.Features, m.Coefficients)
+}
+
+// Summary returns the table of the coefficients and of their inference, or
+// an empty string without inference
+func (m *LinearModel) Summary() string {
+	if m.Inference == nil {
+		return ""
+	}
+	return m.Inference.Table(m.Features, m.Coefficients)
+}
+
+// MarshalBinary implements Model
+func (m *LinearModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeLinearModel(data []byte) (Model, error) {
+	m := &LinearModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode linear model: " + err.Error())
+	}
+	return m, nil
+}
+
+// newLinearModel returns a linear model of the given coefficients, failing
+// if some of them aren't finite, which happens on singular designs
+func newLinearModel(columns *Columns, coeffs []float64) (*LinearModel,
+	error) {
+	for _, c := range coeffs {
+		if math.IsNaN(c) || math.IsInf(c, 0) {
+			return nil, ErrSingular
+		}
+	}
+	m := &LinearModel{Coefficients: coeffs}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	return m, nil
+}
+
+// SolveNormalEquations returns the least squares linear model of the XᵀX and
+// Xᵀy moments of points, X having a leading column of ones for the intercept,
+// so that XᵀX[0][0] is the number of points. The moments are left untouched.
+func SolveNormalEquations(xtx [][]float64, xty []float64, columns *Columns) (
+	*LinearModel, error) {
+	n := len(xty)
+	if n < 2 || len(xtx) != n {
+		return nil, ErrFieldsCount
+	}
+	a := newMatrix(n, n)
+	for i, row := range xtx {
+		if len(row) != n {
+			return nil, ErrFieldsCount
+		}
+		copy(a[i], row)
+	}
+	coeffs, err := solve(a, append([]float64{}, xty...))
+	if err != nil {
+		return nil, err
+	}
+	return newLinearModel(columns, coeffs)
+}
+
+// OLSTrainer fits an ordinary least squares regression with
+// VanillaTrainNamedRegressionModel, and infers the errors of its coefficients
+// unless they can't be estimated. Its parameter is the
+// confidence of the coefficient intervals, DefaultConfidence by default.
+type OLSTrainer struct {
+	Confidence float64
+}
+
+func newOLSTrainer(params Params) (Trainer, error) {
+	if err := params.Check("confidence"); err != nil {
+		return nil, err
+	}
+	confidence, err := params.Float("confidence", DefaultConfidence)
+	if err != nil {
+		return nil, err
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, errors.New("confidence must be between 0 and 1")
+	}
+	return &OLSTrainer{Confidence: confidence}, nil
+}
+
+// Train implements Trainer
+func (t *OLSTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	r, err := VanillaTrainNamedRegressionModel(points, columns)
+	if err != nil {
+		return nil, err
+	}
+	coeffs := make([]float64, len(points[0].Variables)+1)
+	for i := range coeffs {
+		coeffs[i] = r.Coeff(i)
+	}
+	m, err := newLinearModel(columns, coeffs)
+	if err != nil {
+		return nil, err
+	}
+	m.Inference, err = InferFromPoints(points, coeffs, t.Confidence)
+	if err != nil && err != ErrNoInference {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RidgeTrainer fits a linear regression with an L2 penalty of weight Lambda
+// on the coefficients but the intercept, in closed form, and infers the
+// errors of its coefficients unless they can't be estimated. Its parameters
+// are lambda and confidence, as for OLSTrainer.
+type RidgeTrainer struct {
+	Lambda     float64
+	Confidence float64
+}
+
+func newRidgeTrainer(params Params) (Trainer, error) {
+	if err := params.Check("lambda", "confidence"); err != nil {
+		return nil, err
+	}
+	lambda, err := params.Float("lambda", 1)
+	if err != nil {
+		return nil, err
+	}
+	if lambda < 0 {
+		return nil, errors.New("lambda can't be negative")
+	}
+	confidence, err := params.Float("confidence", DefaultConfidence)
+	if err != nil {
+		return nil, err
+	}
+	if confidence <= 0 || confidence >= 1 {
+		return nil, errors.New("confidence must be between 0 and 1")
+	}
+	return &RidgeTrainer{Lambda: lambda, Confidence: confidence}, nil
+}
+
+// Train implements Trainer
+func (t *RidgeTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	a := NewGramAccumulator()
+	for i := range points {
+		if err := a.Add(&points[i]); err != nil {
+			return nil, err
+		}
+	}
+	return a.inferredRidgeModel(t.Lambda, t.Confidence, columns)
+}
+
+// LassoTrainer fits a linear regression minimizing the mean squared error
+// over 2 plus Lambda times the L1 norm of the coefficients but the
+// intercept, by coordinate descent. Its parameters are lambda,
+// maxIterations, 1000 by default, and tolerance, 1e-8 by default. Its
+// coefficients have no inference: they aren't a linear function of the
+// labels, so their covariance has no closed form.
+type LassoTrainer struct {
+	Lambda        float64
+	MaxIterations int
+	Tolerance     float64
+}
+
+func newLassoTrainer(params Params) (Trainer, error) {
+	err := params.Check("lambda", "maxIterations", "tolerance")
+	if err != nil {
+		return nil, err
+	}
+	t := &LassoTrainer{}
+	t.Lambda, err = params.Float("lambda", 1)
+	if err == nil {
+		t.MaxIterations, err = params.Int("maxIterations", 1000)
+	}
+	if err == nil {
+		t.Tolerance, err = params.Float("tolerance", 1e-8)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if t.Lambda < 0 {
+		return nil, errors.New("lambda can't be negative")
+	}
+	return t, nil
+}
+
+// Train implements Trainer. Constant features get a zero coefficient.
+func (t *LassoTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	rows, n := float64(len(points)), len(points[0].Variables)
+	// The features and the label are centered, which leaves the intercept
+	// out of the descent
+	means := make([]float64, n)
+	labelMean := 0.0
+	for _, p := range points {
+		if len(p.Variables) != n {
+			return nil, ErrFieldsCount
+		}
+		for j, v := range p.Variables {
+			means[j] += v / rows
+		}
+		labelMean += p.Label / rows
+	}
+	xs := newMatrix(len(points), n)
+	residuals := make([]float64, len(points))
+	norms := make([]float64, n)
+	for i, p := range points {
+		for j, v := range p.Variables {
+			xs[i][j] = v - means[j]
+			norms[j] += xs[i][j] * xs[i][j] / rows
+		}
+		residuals[i] = p.Label - labelMean
+	}
+
+	beta := make([]float64, n)
+	converged := false
+	for iteration := 0; iteration < t.MaxIterations && !converged; iteration++ {
+		converged = true
+		for j := range beta {
+			if norms[j] < 1e-12 {
+				continue
+			}
+			rho := 0.0
+			for i, x := range xs {
+				rho += x[j] * (residuals[i] + x[j]*beta[j]) / rows
+			}
+			updated := softThreshold(rho, t.Lambda) / norms[j]
+			if delta := updated - beta[j]; delta != 0 {
+				for i, x := range xs {
+					residuals[i] -= x[j] * delta
+				}
+				converged = converged && math.Abs(delta) <= t.Tolerance
+				beta[j] = updated
+			}
+		}
+	}
+	if !converged {
+		return nil, errors.New("lasso didn't converge")
+	}
+	coeffs := append([]float64{labelMean - dot(means, beta)}, beta...)
+	return newLinearModel(columns, coeffs)
+}
+
+// softThreshold shrinks x towards 0 by t
+func softThreshold(x float64, t float64) float64 {
+	switch {
+	case x > t:
+		return x - t
+	case x < -t:
+		return x + t
+	}
+	return 0
+}
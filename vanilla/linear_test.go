@@ -0,0 +1,151 @@
+package vanilla_test
+
+import (
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func linearDataset(t *testing.T) *vanilla.SyntheticDataset {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      300,
+		Intercept: 2,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 3, Max: 10},
+			{Name: "x2", Coefficient: -1, Max: 10},
+			{Name: "x3", Coefficient: 0.01, Max: 10},
+		},
+		Noise: 0.5,
+		Seed:  5,
+	})
+	require.Nil(t, err)
+	return d
+}
+
+// trainModel trains a model with a registered trainer
+func trainModel(t *testing.T, name string, params vanilla.Params,
+	points []vanilla.MlDataPoint) (vanilla.Model, error) {
+	trainer, err := vanilla.NewTrainer(name, params)
+	require.Nil(t, err)
+	return trainer.Train(points, nil)
+}
+
+func train(t *testing.T, name string, params vanilla.Params,
+	points []vanilla.MlDataPoint) (*vanilla.LinearModel, error) {
+	model, err := trainModel(t, name, params, points)
+	if err != nil {
+		return nil, err
+	}
+	return model.(*vanilla.LinearModel), nil
+}
+
+func TestRidgeTrainer(t *testing.T) {
+	d := linearDataset(t)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	ridge, err := train(t, "ridge", vanilla.Params{"lambda": "0"}, d.Points)
+	require.Nil(t, err)
+	for j, c := range ols.Coefficients {
+		require.InDelta(t, c, ridge.Coefficients[j], 1e-6)
+	}
+	ridge, err = train(t, "ridge", vanilla.Params{"lambda": "1e5"}, d.Points)
+	require.Nil(t, err)
+	require.True(t, ridge.Coefficients[1] < ols.Coefficients[1]/2)
+
+	// The design of test1 is singular
+	dataset, err := vanilla.LoadDataset("tests/test1.csv", nil)
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+	_, err = train(t, "ols", nil, points)
+	require.Equal(t, vanilla.ErrSingular, err)
+	_, err = train(t, "ridge", vanilla.Params{"lambda": "0"}, points)
+	require.Equal(t, vanilla.ErrSingular, err)
+	ridge, err = train(t, "ridge", vanilla.Params{"lambda": "0.1"}, points)
+	require.Nil(t, err)
+	require.Equal(t, 4, len(ridge.Coefficients))
+
+	_, err = vanilla.NewTrainer("ridge", vanilla.Params{"lambda": "-1"})
+	require.NotNil(t, err)
+}
+
+func TestLassoTrainer(t *testing.T) {
+	d := linearDataset(t)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	lasso, err := train(t, "lasso", vanilla.Params{"lambda": "0"}, d.Points)
+	require.Nil(t, err)
+	for j, c := range ols.Coefficients {
+		require.InDelta(t, c, lasso.Coefficients[j], 1e-4)
+	}
+	// The penalty zeroes the negligible coefficient of x3
+	lasso, err = train(t, "lasso", vanilla.Params{"lambda": "0.5"}, d.Points)
+	require.Nil(t, err)
+	require.Equal(t, 0.0, lasso.Coefficients[3])
+	require.InDelta(t, 3, lasso.Coefficients[1], 0.2)
+
+	dataset, err := vanilla.LoadDataset("tests/test1.csv", nil)
+	require.Nil(t, err)
+	lasso, err = train(t, "lasso", vanilla.Params{"lambda": "0.1"},
+		dataset.MlDataPoints("test"))
+	require.Nil(t, err)
+	require.Equal(t, 4, len(lasso.Coefficients))
+}
+
+func TestSolveNormalEquations(t *testing.T) {
+	d := linearDataset(t)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	// The ridge trainer without penalty solves the same equations
+	ridge, err := train(t, "ridge", vanilla.Params{"lambda": "0"}, d.Points)
+	require.Nil(t, err)
+
+	xtx := [][]float64{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0},
+		{0, 0, 0, 0}}
+	xty := make([]float64, 4)
+	for _, p := range d.Points {
+		x := append([]float64{1}, p.Variables...)
+		for j := range x {
+			xty[j] += x[j] * p.Label
+			for k := range x {
+				xtx[j][k] += x[j] * x[k]
+			}
+		}
+	}
+	m, err := vanilla.SolveNormalEquations(xtx, xty, nil)
+	require.Nil(t, err)
+	for j, c := range ols.Coefficients {
+		require.InDelta(t, c, m.Coefficients[j], 1e-6)
+		require.InDelta(t, ridge.Coefficients[j], m.Coefficients[j], 1e-9)
+	}
+	require.Equal(t, float64(len(d.Points)), xtx[0][0])
+
+	_, err = vanilla.SolveNormalEquations(xtx[:3], xty, nil)
+	require.Equal(t, vanilla.ErrFieldsCount, err)
+	_, err = vanilla.SolveNormalEquations([][]float64{{1, 1}, {1, 1}},
+		[]float64{1, 1}, nil)
+	require.Equal(t, vanilla.ErrSingular, err)
+}
+
+func TestSingularScale(t *testing.T) {
+	// Features around 1e6 of which one is a third of the other are collinear
+	// up to rounding errors
+	points := make([]vanilla.MlDataPoint, 30)
+	for i := range points {
+		x := 1e6 + float64(i%7)*1e3 + float64(i)
+		points[i] = vanilla.MlDataPoint{Label: float64(i % 2),
+			Variables: []float64{x, x / 3}}
+	}
+	_, err := train(t, "ridge", vanilla.Params{"lambda": "0"}, points)
+	require.Equal(t, vanilla.ErrSingular, err)
+
+	// Features around 1e-7 are fitted
+	for i := range points {
+		x := 1e-7 * float64(i%7)
+		points[i] = vanilla.MlDataPoint{Label: 1 + 2e7*x + float64(i%2),
+			Variables: []float64{x, float64(i % 2)}}
+	}
+	m, err := train(t, "ridge", vanilla.Params{"lambda": "0"}, points)
+	require.Nil(t, err)
+	require.InDelta(t, 2e7, m.Coefficients[1], 1e-3)
+}
@@ -0,0 +1,260 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+)
+
+func init() {
+	RegisterTrainer("logistic", newLogisticTrainer, decodeLogisticModel)
+}
+
+// ErrUnknownLabel is reported for labels that are neither the negative nor
+// the positive label of a binary model
+var ErrUnknownLabel = errors.New("label isn't one of the binary labels")
+
+// LabelMapping maps the labels of a binary outcome to 0 and 1, such as the
+// Coimbra classification where 1 is a control and 2 a patient
+type LabelMapping struct {
+	Negative float64
+	Positive float64
+}
+
+// binary maps a label to 0 or 1
+func (m LabelMapping) binary(label float64) (float64, error) {
+	switch label {
+	case m.Negative:
+		return 0, nil
+	case m.Positive:
+		return 1, nil
+	}
+	return 0, fmt.Errorf("%v: %v", ErrUnknownLabel, label)
+}
+
+// LogisticOptions configure the training of a logistic regression
+type LogisticOptions struct {
+	// Labels map the labels to 0 and 1, they are 0 and 1 if nil
+	Labels *LabelMapping
+	// L2 is the weight of the L2 penalty of the coefficients, the intercept
+	// isn't penalized
+	L2 float64
+	// MaxIterations bounds the Newton iterations, 100 if 0
+	MaxIterations int
+	// Tolerance stops the training once no coefficient changes by more, 1e-8
+	// if 0
+	Tolerance float64
+}
+
+// LogisticModel is a logistic regression model
+type LogisticModel struct {
+	Columns
+	// Coefficients are the intercept followed by a coefficient per feature
+	Coefficients []float64
+	Labels       LabelMapping
+	// Iterations is the number of Newton iterations of the training, which
+	// converged unless it reached the maximum
+	Iterations int
+	Converged  bool
+}
+
+// TrainLogisticModel fits a logistic regression on points by iteratively
+// reweighted least squares. columns and opts may be nil.
+func TrainLogisticModel(points []MlDataPoint, columns *Columns,
+	opts *LogisticOptions) (*LogisticModel, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	if opts == nil {
+		opts = &LogisticOptions{}
+	}
+	m := &LogisticModel{Labels: LabelMapping{0, 1}}
+	if opts.Labels != nil {
+		m.Labels = *opts.Labels
+	}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	maxIterations, tolerance := opts.MaxIterations, opts.Tolerance
+	if maxIterations == 0 {
+		maxIterations = 100
+	}
+	if tolerance == 0 {
+		tolerance = 1e-8
+	}
+
+	n := len(points[0].Variables) + 1
+	xs := make([][]float64, len(points))
+	ys := make([]float64, len(points))
+	for i, p := range points {
+		if len(p.Variables) != n-1 {
+			return nil, ErrFieldsCount
+		}
+		y, err := m.Labels.binary(p.Label)
+		if err != nil {
+			return nil, err
+		}
+		xs[i], ys[i] = withIntercept(p.Variables), y
+	}
+
+	beta := make([]float64, n)
+	for m.Iterations < maxIterations && !m.Converged {
+		m.Iterations++
+		hessian := newMatrix(n, n)
+		gradient := make([]float64, n)
+		for i, x := range xs {
+			p := sigmoid(dot(x, beta))
+			w := p * (1 - p)
+			for j := range x {
+				gradient[j] += (ys[i] - p) * x[j]
+				for k := range x {
+					hessian[j][k] += w * x[j] * x[k]
+				}
+			}
+		}
+		for j := 1; j < n; j++ {
+			gradient[j] -= opts.L2 * beta[j]
+			hessian[j][j] += opts.L2
+		}
+		step, err := solve(hessian, gradient)
+		if err != nil {
+			return nil, errors.New("couldn't fit logistic regression: " +
+				err.Error())
+		}
+		m.Converged = true
+		for j := range beta {
+			beta[j] += step[j]
+			if math.IsNaN(beta[j]) || math.IsInf(beta[j], 0) {
+				return nil, errors.New("logistic regression diverged")
+			}
+			if math.Abs(step[j]) > tolerance {
+				m.Converged = false
+			}
+		}
+	}
+	m.Coefficients = beta
+	return m, nil
+}
+
+// sigmoid returns the logistic function of z
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
+
+// Probability returns the probability that a point with the given variables
+// has the positive label
+func (m *LogisticModel) Probability(variables []float64) (float64, error) {
+	if len(variables) != len(m.Coefficients)-1 {
+		return 0, ErrFieldsCount
+	}
+	return sigmoid(dot(withIntercept(variables), m.Coefficients)), nil
+}
+
+// Predict returns the most likely label of a point with the given variables
+func (m *LogisticModel) Predict(variables []float64) (float64, error) {
+	p, err := m.Probability(variables)
+	if err != nil {
+		return 0, err
+	}
+	if p >= 0.5 {
+		return m.Labels.Positive, nil
+	}
+	return m.Labels.Negative, nil
+}
+
+// BinaryLabels implements BinaryClassifier
+func (m *LogisticModel) BinaryLabels() LabelMapping {
+	return m.Labels
+}
+
+// Describe implements Model, it returns the formula of the log-odds of the
+// positive label
+func (m *LogisticModel) Describe() string {
+	return linearFormula("logit(P)", m.Features, m.Coefficients)
+}
+
+// MarshalBinary implements Model
+func (m *LogisticModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeLogisticModel(data []byte) (Model, error) {
+	m := &LogisticModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode logistic model: " +
+			err.Error())
+	}
+	return m, nil
+}
+
+// LogisticTrainer trains logistic regressions. Its parameters are the
+// LogisticOptions l2, maxIterations and tolerance, and the negative and
+// positive labels.
+type LogisticTrainer struct {
+	Options LogisticOptions
+}
+
+func newLogisticTrainer(params Params) (Trainer, error) {
+	err := params.Check("l2", "maxIterations", "tolerance", "negative",
+		"positive")
+	if err != nil {
+		return nil, err
+	}
+	t := &LogisticTrainer{}
+	labels := LabelMapping{}
+	t.Options.L2, err = params.Float("l2", 0)
+	if err == nil {
+		t.Options.MaxIterations, err = params.Int("maxIterations", 0)
+	}
+	if err == nil {
+		t.Options.Tolerance, err = params.Float("tolerance", 0)
+	}
+	if err == nil {
+		labels.Negative, err = params.Float("negative", 0)
+	}
+	if err == nil {
+		labels.Positive, err = params.Float("positive", 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o := &t.Options
+	if o.L2 < 0 || o.MaxIterations < 0 || o.Tolerance < 0 {
+		return nil, errors.New("l2, maxIterations and tolerance can't be " +
+			"negative")
+	}
+	if labels.Negative == labels.Positive {
+		return nil, errors.New("the negative and positive labels must differ")
+	}
+	o.Labels = &labels
+	return t, nil
+}
+
+// Train implements Trainer, it fails if the training doesn't converge
+func (t *LogisticTrainer) Train(points []MlDataPoint, columns *Columns) (
+	Model, error) {
+	m, err := TrainLogisticModel(points, columns, &t.Options)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Converged {
+		return nil, errors.New("logistic regression didn't converge")
+	}
+	return m, nil
+}
+
+// linearFormula formats a linear model like the formulas of
+// regression.Regression, naming the features by their index if names is
+// empty
+func linearFormula(output string, names []string, coeffs []float64) string {
+	formula := fmt.Sprintf("%s = %.2f", output, coeffs[0])
+	for j, c := range coeffs[1:] {
+		name := fmt.Sprintf("X%d", j)
+		if j < len(names) {
+			name = names[j]
+		}
+		formula += fmt.Sprintf(" + %v*%.2f", name, c)
+	}
+	return formula
+}
@@ -0,0 +1,54 @@
+package vanilla_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainLogisticModel(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      5000,
+		Model:     vanilla.Logistic,
+		Intercept: -1,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 2, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -3, Min: -1, Max: 1},
+		},
+		Seed: 1,
+	})
+	require.Nil(t, err)
+	// Label the outcome like the Coimbra classification
+	for i := range d.Points {
+		d.Points[i].Label++
+	}
+	labels := &vanilla.LabelMapping{Negative: 1, Positive: 2}
+
+	m, err := vanilla.TrainLogisticModel(d.Points, &d.Columns,
+		&vanilla.LogisticOptions{Labels: labels})
+	require.Nil(t, err)
+	require.True(t, m.Converged)
+	require.InDelta(t, -1, m.Coefficients[0], 0.2)
+	require.InDelta(t, 2, m.Coefficients[1], 0.3)
+	require.InDelta(t, -3, m.Coefficients[2], 0.3)
+	require.Contains(t, m.Describe(), "logit(P) = ")
+
+	p, err := m.Probability([]float64{1, -1})
+	require.Nil(t, err)
+	require.True(t, p > 0.95)
+	label, err := m.Predict([]float64{-1, 1})
+	require.Nil(t, err)
+	require.Equal(t, 1.0, label)
+
+	// The penalty shrinks the coefficients
+	penalized, err := vanilla.TrainLogisticModel(d.Points, &d.Columns,
+		&vanilla.LogisticOptions{Labels: labels, L2: 1000})
+	require.Nil(t, err)
+	require.True(t, math.Abs(penalized.Coefficients[2]) <
+		math.Abs(m.Coefficients[2]))
+
+	_, err = vanilla.TrainLogisticModel(d.Points, nil, nil)
+	require.NotNil(t, err)
+}
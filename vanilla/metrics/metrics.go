@@ -0,0 +1,271 @@
+// Package metrics evaluates the predictions of trained models against the
+// true labels of held-out points.
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// ErrLengths is reported when the predictions don't match the labels
+var ErrLengths = errors.New("labels and predictions have different lengths")
+
+// ErrSingleClass is reported by ROCAUC when the points are all positive or
+// all negative
+var ErrSingleClass = errors.New("ROC AUC needs positive and negative points")
+
+// check returns an error if there are no labels or if the predictions don't
+// match them
+func check(actual []float64, predicted []float64) error {
+	if len(actual) != len(predicted) {
+		return ErrLengths
+	}
+	if len(actual) == 0 {
+		return errors.New("no labels to evaluate")
+	}
+	return nil
+}
+
+// Regression are the metrics of a regression model
+type Regression struct {
+	Count int
+	// MSE, RMSE and MAE are the mean squared, root mean squared and mean
+	// absolute errors
+	MSE  float64
+	RMSE float64
+	MAE  float64
+	// R2 is the coefficient of determination, NaN if the labels are constant
+	R2 float64
+}
+
+// ComputeRegression computes the regression metrics of predictions
+func ComputeRegression(actual []float64, predicted []float64) (*Regression,
+	error) {
+	if err := check(actual, predicted); err != nil {
+		return nil, err
+	}
+	n := float64(len(actual))
+	mean := 0.0
+	for _, y := range actual {
+		mean += y / n
+	}
+	m := &Regression{Count: len(actual)}
+	total := 0.0
+	for i, y := range actual {
+		e := y - predicted[i]
+		m.MSE += e * e
+		m.MAE += math.Abs(e)
+		total += (y - mean) * (y - mean)
+	}
+	m.R2 = math.NaN()
+	if total > 0 {
+		m.R2 = 1 - m.MSE/total
+	}
+	m.MSE /= n
+	m.MAE /= n
+	m.RMSE = math.Sqrt(m.MSE)
+	return m, nil
+}
+
+// MarshalJSON encodes the metrics, an undefined R2 being null
+func (m *Regression) MarshalJSON() ([]byte, error) {
+	type regression Regression
+	return json.Marshal(&struct {
+		*regression
+		R2 *float64
+	}{(*regression)(m), finite(m.R2)})
+}
+
+// finite returns nil for values that json can't encode
+func finite(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// ConfusionMatrix counts the points by true and predicted label
+type ConfusionMatrix struct {
+	// Labels are the sorted labels of the rows and columns
+	Labels []float64
+	// Counts[i][j] is the number of points of label i predicted as label j
+	Counts [][]int
+}
+
+// NewConfusionMatrix returns the confusion matrix of predictions
+func NewConfusionMatrix(actual []float64, predicted []float64) (
+	*ConfusionMatrix, error) {
+	if err := check(actual, predicted); err != nil {
+		return nil, err
+	}
+	index := make(map[float64]int)
+	for _, labels := range [][]float64{actual, predicted} {
+		for _, l := range labels {
+			index[l] = 0
+		}
+	}
+	c := &ConfusionMatrix{}
+	for l := range index {
+		c.Labels = append(c.Labels, l)
+	}
+	sort.Float64s(c.Labels)
+	c.Counts = make([][]int, len(c.Labels))
+	for i, l := range c.Labels {
+		index[l] = i
+		c.Counts[i] = make([]int, len(c.Labels))
+	}
+	for i, y := range actual {
+		c.Counts[index[y]][index[predicted[i]]]++
+	}
+	return c, nil
+}
+
+// Count returns the number of points of a true label predicted as another
+func (c *ConfusionMatrix) Count(actual float64, predicted float64) int {
+	i, j := -1, -1
+	for k, l := range c.Labels {
+		if l == actual {
+			i = k
+		}
+		if l == predicted {
+			j = k
+		}
+	}
+	if i < 0 || j < 0 {
+		return 0
+	}
+	return c.Counts[i][j]
+}
+
+// Classification are the metrics of a classifier
+type Classification struct {
+	Count    int
+	Accuracy float64
+	// MultiClass tells that the classifier predicts more than two labels, so
+	// that only the accuracy and the confusion matrix are given
+	MultiClass bool `json:",omitempty"`
+	// Positive is the label whose precision, recall and F1 score are given,
+	// which are 0 when undefined
+	Positive  float64
+	Precision float64
+	Recall    float64
+	F1        float64
+	// AUC is the area under the ROC curve, only given with scores
+	AUC       *float64 `json:",omitempty"`
+	Confusion *ConfusionMatrix
+}
+
+// ComputeClassification computes the metrics of predicted labels. scores,
+// which may be nil, are the scores of the positive label, such as predicted
+// probabilities, and give the ROC AUC unless the points are all of one class,
+// as in a small held-out part of a dataset.
+func ComputeClassification(actual []float64, predicted []float64,
+	positive float64, scores []float64) (*Classification, error) {
+	confusion, err := NewConfusionMatrix(actual, predicted)
+	if err != nil {
+		return nil, err
+	}
+	m := &Classification{Count: len(actual), Positive: positive,
+		Confusion: confusion}
+	correct, tp, fp, fn := 0, 0, 0, 0
+	for i, y := range actual {
+		if y == predicted[i] {
+			correct++
+		}
+		switch {
+		case y == positive && predicted[i] == positive:
+			tp++
+		case predicted[i] == positive:
+			fp++
+		case y == positive:
+			fn++
+		}
+	}
+	m.Accuracy = float64(correct) / float64(len(actual))
+	if tp > 0 {
+		m.Precision = float64(tp) / float64(tp+fp)
+		m.Recall = float64(tp) / float64(tp+fn)
+		m.F1 = 2 * m.Precision * m.Recall / (m.Precision + m.Recall)
+	}
+	if scores != nil {
+		auc, err := ROCAUC(actual, positive, scores)
+		if err == nil {
+			m.AUC = &auc
+		} else if err != ErrSingleClass {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ComputeMultiClassification computes the accuracy and the confusion matrix
+// of labels predicted by a classifier of more than two labels
+func ComputeMultiClassification(actual []float64, predicted []float64) (
+	*Classification, error) {
+	confusion, err := NewConfusionMatrix(actual, predicted)
+	if err != nil {
+		return nil, err
+	}
+	correct := 0
+	for i, y := range actual {
+		if y == predicted[i] {
+			correct++
+		}
+	}
+	return &Classification{Count: len(actual),
+		Accuracy: float64(correct) / float64(len(actual)), MultiClass: true,
+		Confusion: confusion}, nil
+}
+
+// ROCAUC returns the area under the ROC curve of the scores of the positive
+// label, which is the probability that a positive point scores higher than a
+// negative one, ties counting for half
+func ROCAUC(actual []float64, positive float64, scores []float64) (float64,
+	error) {
+	if err := check(actual, scores); err != nil {
+		return 0, err
+	}
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return scores[order[i]] < scores[order[j]]
+	})
+	// Sum the ranks of the positive points, tied scores sharing their
+	// average rank
+	positives, negatives, ranks := 0, 0, 0.0
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && scores[order[end]] == scores[order[start]] {
+			end++
+		}
+		rank := float64(start+end+1) / 2
+		for _, i := range order[start:end] {
+			if actual[i] == positive {
+				positives++
+				ranks += rank
+			} else {
+				negatives++
+			}
+		}
+		start = end
+	}
+	if positives == 0 || negatives == 0 {
+		return 0, ErrSingleClass
+	}
+	p, n := float64(positives), float64(negatives)
+	return (ranks - p*(p+1)/2) / (p * n), nil
+}
+
+// Save writes metrics as json to a file
+func Save(metrics interface{}, fileName string) error {
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode metrics: " + err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
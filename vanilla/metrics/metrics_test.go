@@ -0,0 +1,91 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla/metrics"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeRegression(t *testing.T) {
+	m, err := metrics.ComputeRegression([]float64{1, 2, 3, 4},
+		[]float64{1, 3, 3, 2})
+	require.Nil(t, err)
+	require.Equal(t, 4, m.Count)
+	require.Equal(t, 1.25, m.MSE)
+	require.Equal(t, math.Sqrt(1.25), m.RMSE)
+	require.Equal(t, 0.75, m.MAE)
+	require.Equal(t, 0.0, m.R2)
+
+	_, err = metrics.ComputeRegression([]float64{1}, []float64{1, 2})
+	require.Equal(t, metrics.ErrLengths, err)
+
+	// Constant labels have no R2, which is encoded as null
+	m, err = metrics.ComputeRegression([]float64{1, 1}, []float64{1, 2})
+	require.Nil(t, err)
+	require.True(t, math.IsNaN(m.R2))
+	data, err := json.Marshal(m)
+	require.Nil(t, err)
+	require.Contains(t, string(data), `"R2":null`)
+	require.Contains(t, string(data), `"MSE":0.5`)
+}
+
+func TestComputeClassification(t *testing.T) {
+	actual := []float64{1, 1, 1, 2, 2, 2, 2, 2}
+	predicted := []float64{1, 1, 2, 2, 2, 2, 1, 1}
+	scores := []float64{0.1, 0.2, 0.6, 0.9, 0.8, 0.7, 0.3, 0.4}
+	m, err := metrics.ComputeClassification(actual, predicted, 2, scores)
+	require.Nil(t, err)
+	require.Equal(t, 5.0/8, m.Accuracy)
+	require.Equal(t, 3.0/4, m.Precision)
+	require.Equal(t, 3.0/5, m.Recall)
+	require.InDelta(t, 2.0/3, m.F1, 1e-12)
+	// 13 of the 15 positive-negative pairs are ranked correctly
+	require.InDelta(t, 13.0/15, *m.AUC, 1e-12)
+	require.Equal(t, []float64{1, 2}, m.Confusion.Labels)
+	require.Equal(t, [][]int{{2, 1}, {2, 3}}, m.Confusion.Counts)
+	require.Equal(t, 2, m.Confusion.Count(2, 1))
+
+	m, err = metrics.ComputeClassification(actual, predicted, 2, nil)
+	require.Nil(t, err)
+	require.Nil(t, m.AUC)
+
+	// Points of a single class have no AUC
+	m, err = metrics.ComputeClassification([]float64{2, 2}, []float64{2, 1},
+		2, []float64{0.9, 0.4})
+	require.Nil(t, err)
+	require.Nil(t, m.AUC)
+	require.Equal(t, 0.5, m.Accuracy)
+	_, err = metrics.ComputeClassification([]float64{2, 1}, []float64{2, 1},
+		2, []float64{0.9})
+	require.NotNil(t, err)
+}
+
+func TestComputeMultiClassification(t *testing.T) {
+	m, err := metrics.ComputeMultiClassification([]float64{0, 1, 2, 2},
+		[]float64{0, 2, 2, 1})
+	require.Nil(t, err)
+	require.True(t, m.MultiClass)
+	require.Equal(t, 4, m.Count)
+	require.Equal(t, 0.5, m.Accuracy)
+	require.Nil(t, m.AUC)
+	require.Equal(t, [][]int{{1, 0, 0}, {0, 0, 1}, {0, 1, 1}},
+		m.Confusion.Counts)
+	_, err = metrics.ComputeMultiClassification([]float64{0}, nil)
+	require.Equal(t, metrics.ErrLengths, err)
+}
+
+func TestROCAUC(t *testing.T) {
+	auc, err := metrics.ROCAUC([]float64{0, 0, 1, 1}, 1,
+		[]float64{0.5, 0.5, 0.5, 0.5})
+	require.Nil(t, err)
+	require.Equal(t, 0.5, auc)
+	auc, err = metrics.ROCAUC([]float64{0, 0, 1, 1}, 1,
+		[]float64{0.1, 0.2, 0.3, 0.4})
+	require.Nil(t, err)
+	require.Equal(t, 1.0, auc)
+	_, err = metrics.ROCAUC([]float64{1, 1}, 1, []float64{0.1, 0.2})
+	require.Equal(t, metrics.ErrSingleClass, err)
+}
@@ -0,0 +1,344 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterTrainer("naivebayes", newNaiveBayesTrainer, decodeNaiveBayesModel)
+}
+
+// ClassStatistics are the aggregates of the points of a class from which a
+// Gaussian naive Bayes classifier is trained, without the points themselves.
+// MergeClassStatistics adds up those of several providers.
+type ClassStatistics struct {
+	Label float64
+	Count int
+	// Sums and SumSquares are the sums of every feature and of its square
+	Sums       []float64
+	SumSquares []float64
+}
+
+// ComputeClassStatistics returns the statistics of every class of points,
+// sorted by label
+func ComputeClassStatistics(points []MlDataPoint) ([]ClassStatistics,
+	error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	n := len(points[0].Variables)
+	byLabel := make(map[float64]*ClassStatistics)
+	for _, p := range points {
+		if len(p.Variables) != n {
+			return nil, ErrFieldsCount
+		}
+		c, ok := byLabel[p.Label]
+		if !ok {
+			c = &ClassStatistics{Label: p.Label, Sums: make([]float64, n),
+				SumSquares: make([]float64, n)}
+			byLabel[p.Label] = c
+		}
+		c.Count++
+		for j, v := range p.Variables {
+			c.Sums[j] += v
+			c.SumSquares[j] += v * v
+		}
+	}
+	var stats []ClassStatistics
+	for _, c := range byLabel {
+		stats = append(stats, *c)
+	}
+	sortClasses(stats)
+	return stats, nil
+}
+
+// MergeClassStatistics adds the statistics of several sets of points, such as
+// the points of different providers
+func MergeClassStatistics(sets ...[]ClassStatistics) ([]ClassStatistics,
+	error) {
+	byLabel := make(map[float64]*ClassStatistics)
+	n := -1
+	for _, set := range sets {
+		for _, c := range set {
+			if n == -1 {
+				n = len(c.Sums)
+			}
+			if len(c.Sums) != n || len(c.SumSquares) != n {
+				return nil, ErrFieldsCount
+			}
+			merged, ok := byLabel[c.Label]
+			if !ok {
+				merged = &ClassStatistics{Label: c.Label,
+					Sums: make([]float64, n), SumSquares: make([]float64, n)}
+				byLabel[c.Label] = merged
+			}
+			merged.Count += c.Count
+			for j := range c.Sums {
+				merged.Sums[j] += c.Sums[j]
+				merged.SumSquares[j] += c.SumSquares[j]
+			}
+		}
+	}
+	var stats []ClassStatistics
+	for _, c := range byLabel {
+		stats = append(stats, *c)
+	}
+	sortClasses(stats)
+	return stats, nil
+}
+
+func sortClasses(stats []ClassStatistics) {
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Label < stats[j].Label
+	})
+}
+
+// NaiveBayesClass are the prior and the per-feature normal distribution of a
+// class
+type NaiveBayesClass struct {
+	Label     float64
+	Prior     float64
+	Means     []float64
+	Variances []float64
+}
+
+// NaiveBayesModel is a Gaussian naive Bayes classifier
+type NaiveBayesModel struct {
+	Columns
+	// Classes are sorted by label
+	Classes []NaiveBayesClass
+}
+
+// NaiveBayesFromStatistics returns the classifier of the points summed up by
+// stats. smoothing, which must be positive, times the largest variance of the
+// features is added to every variance, which keeps constant features, such
+// as those of a class of a single point, from giving zero variances. columns
+// may be nil.
+func NaiveBayesFromStatistics(stats []ClassStatistics, columns *Columns,
+	smoothing float64) (*NaiveBayesModel, error) {
+	if len(stats) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	if smoothing <= 0 {
+		return nil, errors.New("smoothing must be positive")
+	}
+	m := &NaiveBayesModel{}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	n, total := len(stats[0].Sums), 0
+	sums, sumSquares := make([]float64, n), make([]float64, n)
+	for _, c := range stats {
+		if len(c.Sums) != n || len(c.SumSquares) != n {
+			return nil, ErrFieldsCount
+		}
+		if c.Count <= 0 {
+			return nil, fmt.Errorf("class %v has no points", c.Label)
+		}
+		total += c.Count
+		for j := range c.Sums {
+			sums[j] += c.Sums[j]
+			sumSquares[j] += c.SumSquares[j]
+		}
+	}
+	largest := 0.0
+	for j := range sums {
+		largest = math.Max(largest, variance(float64(total), sums[j],
+			sumSquares[j]))
+	}
+	epsilon := smoothing * largest
+	if epsilon == 0 {
+		// Every feature is constant
+		epsilon = smoothing
+	}
+
+	for _, c := range stats {
+		count := float64(c.Count)
+		class := NaiveBayesClass{Label: c.Label,
+			Prior: count / float64(total), Means: make([]float64, n),
+			Variances: make([]float64, n)}
+		for j := range c.Sums {
+			class.Means[j] = c.Sums[j] / count
+			class.Variances[j] = variance(count, c.Sums[j], c.SumSquares[j]) +
+				epsilon
+		}
+		m.Classes = append(m.Classes, class)
+	}
+	sort.Slice(m.Classes, func(i, j int) bool {
+		return m.Classes[i].Label < m.Classes[j].Label
+	})
+	return m, nil
+}
+
+// variance returns the population variance of count values of the given sum
+// and sum of squares, rounding errors aside
+func variance(count float64, sum float64, sumSquares float64) float64 {
+	m := sum / count
+	return math.Max(sumSquares/count-m*m, 0)
+}
+
+// TrainNaiveBayes trains a Gaussian naive Bayes classifier on points, see
+// NaiveBayesFromStatistics. columns may be nil.
+func TrainNaiveBayes(points []MlDataPoint, columns *Columns,
+	smoothing float64) (*NaiveBayesModel, error) {
+	stats, err := ComputeClassStatistics(points)
+	if err != nil {
+		return nil, err
+	}
+	return NaiveBayesFromStatistics(stats, columns, smoothing)
+}
+
+// logPosteriors returns the log of the unnormalized posterior probability of
+// every class of a point
+func (m *NaiveBayesModel) logPosteriors(variables []float64) ([]float64,
+	error) {
+	if len(m.Classes) == 0 || len(variables) != len(m.Classes[0].Means) {
+		return nil, ErrFieldsCount
+	}
+	logs := make([]float64, len(m.Classes))
+	for i, c := range m.Classes {
+		logs[i] = math.Log(c.Prior)
+		for j, x := range variables {
+			d := x - c.Means[j]
+			logs[i] -= 0.5*math.Log(2*math.Pi*c.Variances[j]) +
+				d*d/(2*c.Variances[j])
+		}
+	}
+	return logs, nil
+}
+
+// Predict implements Model, it returns the most likely class of a point
+func (m *NaiveBayesModel) Predict(variables []float64) (float64, error) {
+	logs, err := m.logPosteriors(variables)
+	if err != nil {
+		return 0, err
+	}
+	best := 0
+	for i := range logs {
+		if logs[i] > logs[best] {
+			best = i
+		}
+	}
+	return m.Classes[best].Label, nil
+}
+
+// Probabilities returns the posterior probability of every class of a point,
+// in the order of Classes
+func (m *NaiveBayesModel) Probabilities(variables []float64) ([]float64,
+	error) {
+	logs, err := m.logPosteriors(variables)
+	if err != nil {
+		return nil, err
+	}
+	largest := math.Inf(-1)
+	for _, l := range logs {
+		largest = math.Max(largest, l)
+	}
+	total := 0.0
+	for i := range logs {
+		logs[i] = math.Exp(logs[i] - largest)
+		total += logs[i]
+	}
+	for i := range logs {
+		logs[i] /= total
+	}
+	return logs, nil
+}
+
+// Probability returns the probability of the positive label, the largest,
+// of a binary classifier
+func (m *NaiveBayesModel) Probability(variables []float64) (float64, error) {
+	if len(m.Classes) != 2 {
+		return 0, fmt.Errorf("classifier has %d classes, not 2",
+			len(m.Classes))
+	}
+	p, err := m.Probabilities(variables)
+	if err != nil {
+		return 0, err
+	}
+	return p[1], nil
+}
+
+// BinaryLabels implements BinaryClassifier, the labels of a binary classifier
+// being its smallest and largest labels
+func (m *NaiveBayesModel) BinaryLabels() LabelMapping {
+	return binaryLabels(m.ClassLabels())
+}
+
+// ClassLabels implements Classifier
+func (m *NaiveBayesModel) ClassLabels() []float64 {
+	classes := make([]float64, len(m.Classes))
+	for i, c := range m.Classes {
+		classes[i] = c.Label
+	}
+	return classes
+}
+
+// Binary tells if the classifier has two classes
+func (m *NaiveBayesModel) Binary() bool {
+	return len(m.Classes) == 2
+}
+
+// Describe implements Model, it returns the prior and the means of every
+// class
+func (m *NaiveBayesModel) Describe() string {
+	classes := make([]string, len(m.Classes))
+	for i, c := range m.Classes {
+		means := make([]string, len(c.Means))
+		for j, mean := range c.Means {
+			name := fmt.Sprintf("X%d", j)
+			if j < len(m.Features) {
+				name = m.Features[j]
+			}
+			means[j] = fmt.Sprintf("%v=%.2f", name, mean)
+		}
+		classes[i] = fmt.Sprintf("%v (prior %.2f): %s", c.Label, c.Prior,
+			strings.Join(means, ", "))
+	}
+	return "Naive Bayes " + strings.Join(classes, "; ")
+}
+
+// MarshalBinary implements Model
+func (m *NaiveBayesModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeNaiveBayesModel(data []byte) (Model, error) {
+	m := &NaiveBayesModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode naive Bayes model: " +
+			err.Error())
+	}
+	return m, nil
+}
+
+// NaiveBayesTrainer trains Gaussian naive Bayes classifiers. Its parameter is
+// the variance smoothing, 1e-9 by default.
+type NaiveBayesTrainer struct {
+	Smoothing float64
+}
+
+func newNaiveBayesTrainer(params Params) (Trainer, error) {
+	if err := params.Check("smoothing"); err != nil {
+		return nil, err
+	}
+	smoothing, err := params.Float("smoothing", 1e-9)
+	if err != nil {
+		return nil, err
+	}
+	if smoothing <= 0 {
+		return nil, errors.New("smoothing must be positive")
+	}
+	return &NaiveBayesTrainer{Smoothing: smoothing}, nil
+}
+
+// Train implements Trainer
+func (t *NaiveBayesTrainer) Train(points []MlDataPoint, columns *Columns) (
+	Model, error) {
+	return TrainNaiveBayes(points, columns, t.Smoothing)
+}
@@ -0,0 +1,110 @@
+package vanilla_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaiveBayes(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+	stats, err := vanilla.ComputeClassStatistics(points)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(stats))
+	require.Equal(t, 1.0, stats[0].Label)
+	require.Equal(t, 3, stats[0].Count)
+	require.Equal(t, []float64{22.5, 15, 18}, stats[0].Sums)
+
+	// The statistics of two providers add up to those of all the points
+	first, err := vanilla.ComputeClassStatistics(points[:2])
+	require.Nil(t, err)
+	second, err := vanilla.ComputeClassStatistics(points[2:])
+	require.Nil(t, err)
+	merged, err := vanilla.MergeClassStatistics(first, second)
+	require.Nil(t, err)
+	require.Equal(t, stats, merged)
+
+	m, err := vanilla.NaiveBayesFromStatistics(merged, &dataset.Columns, 1e-9)
+	require.Nil(t, err)
+	trained, err := trainModel(t, "naivebayes", nil, points)
+	require.Nil(t, err)
+	trained.(*vanilla.NaiveBayesModel).Columns = dataset.Columns
+	require.Equal(t, m, trained)
+	require.InDelta(t, 0.6, m.Classes[0].Prior, 1e-9)
+	require.Contains(t, m.Describe(), "field1=7.50")
+
+	data, err := m.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("naivebayes", data)
+	require.Nil(t, err)
+	require.Equal(t, m, decoded)
+
+	_, err = m.Predict([]float64{1})
+	require.Equal(t, vanilla.ErrFieldsCount, err)
+	_, err = vanilla.NaiveBayesFromStatistics(nil, nil, 1e-9)
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("naivebayes",
+		vanilla.Params{"smoothing": "-1"})
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("naivebayes",
+		vanilla.Params{"smoothing": "0"})
+	require.NotNil(t, err)
+	_, err = vanilla.NaiveBayesFromStatistics(merged, nil, 0)
+	require.NotNil(t, err)
+}
+
+func TestNaiveBayesSinglePointClass(t *testing.T) {
+	// The class of a single point has zero variances before smoothing
+	points := []vanilla.MlDataPoint{{Label: 0, Variables: []float64{1, 2}},
+		{Label: 1, Variables: []float64{5, 1}},
+		{Label: 1, Variables: []float64{6, 3}}}
+	m, err := vanilla.TrainNaiveBayes(points, nil, 1e-9)
+	require.Nil(t, err)
+	probabilities, err := m.Probabilities([]float64{1, 2})
+	require.Nil(t, err)
+	for _, p := range probabilities {
+		require.False(t, math.IsNaN(p))
+	}
+	predicted, err := m.Predict([]float64{1, 2})
+	require.Nil(t, err)
+	require.Equal(t, 0.0, predicted)
+	predicted, err = m.Predict([]float64{5.5, 2})
+	require.Nil(t, err)
+	require.Equal(t, 1.0, predicted)
+}
+
+func TestNaiveBayesAgainstLogistic(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:  2000,
+		Model: vanilla.Logistic,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 6, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -4, Min: -1, Max: 1},
+		},
+		Seed: 6,
+	})
+	require.Nil(t, err)
+	// Label the outcome like the Coimbra classification
+	for i := range d.Points {
+		d.Points[i].Label++
+	}
+	train, test := d.Points[:1500], d.Points[1500:]
+
+	nb, err := trainModel(t, "naivebayes", nil, train)
+	require.Nil(t, err)
+	logistic, err := trainModel(t, "logistic",
+		vanilla.Params{"negative": "1", "positive": "2"}, train)
+	require.Nil(t, err)
+	nbEvaluation, err := vanilla.EvaluateModel(nb, test)
+	require.Nil(t, err)
+	logisticEvaluation, err := vanilla.EvaluateModel(logistic, test)
+	require.Nil(t, err)
+	require.Equal(t, 2.0, nbEvaluation.Classification.Positive)
+	require.True(t, nbEvaluation.Classification.Accuracy > 0.75)
+	require.InDelta(t, logisticEvaluation.Classification.Accuracy,
+		nbEvaluation.Classification.Accuracy, 0.05)
+}
@@ -0,0 +1,276 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
+	"sort"
+)
+
+// PCA is the principal component analysis of the features of data points
+type PCA struct {
+	// Features are the names of the analysed features, in order
+	Features []string
+	Count    int
+	Means    []float64
+	// Covariance is the sample covariance matrix of the features
+	Covariance [][]float64
+	// Components are the principal axes, unit vectors sorted by decreasing
+	// variance, and Variances the variances of the points along them
+	Components [][]float64
+	Variances  []float64
+	// ExplainedRatio is the fraction of the total variance of every component
+	ExplainedRatio []float64
+}
+
+// PCAFromPoints analyses the features of points, named by features, which may
+// be nil
+func PCAFromPoints(points []MlDataPoint, features []string) (*PCA, error) {
+	a := NewGramAccumulator()
+	for i := range points {
+		if err := a.Add(&points[i]); err != nil {
+			return nil, err
+		}
+	}
+	return PCAFromMoments(a.XtX, features)
+}
+
+// PCAFromMoments analyses the features of points summed up by their XᵀX
+// second moments, X having a leading column of ones, as aggregated by a
+// GramAccumulator or a Prio linReg field. features may be nil.
+func PCAFromMoments(xtx [][]float64, features []string) (*PCA, error) {
+	if len(xtx) < 2 {
+		return nil, ErrFieldsCount
+	}
+	d := len(xtx) - 1
+	n := xtx[0][0]
+	if n < 2 {
+		return nil, errors.New("not enough points to analyse")
+	}
+	p := &PCA{Features: features, Count: int(math.Round(n)),
+		Means: make([]float64, d), Covariance: newMatrix(d, d)}
+	for j := 0; j < d; j++ {
+		if len(xtx[j+1]) != d+1 {
+			return nil, ErrFieldsCount
+		}
+		p.Means[j] = xtx[0][j+1] / n
+	}
+	for j := 0; j < d; j++ {
+		for k := 0; k < d; k++ {
+			p.Covariance[j][k] = (xtx[j+1][k+1] - n*p.Means[j]*p.Means[k]) /
+				(n - 1)
+		}
+	}
+	values, vectors := symmetricEigen(p.Covariance)
+	order := make([]int, d)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return values[order[i]] > values[order[j]]
+	})
+	total := 0.0
+	for _, v := range values {
+		total += math.Max(v, 0)
+	}
+	for _, i := range order {
+		component := make([]float64, d)
+		for j := range component {
+			component[j] = vectors[j][i]
+		}
+		variance := math.Max(values[i], 0)
+		p.Components = append(p.Components, component)
+		p.Variances = append(p.Variances, variance)
+		ratio := 0.0
+		if total > 0 {
+			ratio = variance / total
+		}
+		p.ExplainedRatio = append(p.ExplainedRatio, ratio)
+	}
+	return p, nil
+}
+
+// LoadPCA reads an analysis saved by PCA.Save
+func LoadPCA(fileName string) (*PCA, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	p := &PCA{}
+	err = json.Unmarshal(data, p)
+	if err != nil {
+		return nil, errors.New("couldn't decode principal components: " +
+			err.Error())
+	}
+	return p, nil
+}
+
+// Save writes the analysis as json to a file
+func (p *PCA) Save(fileName string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode principal components: " +
+			err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// ComponentsFor returns the smallest number of components explaining at least
+// ratio of the total variance
+func (p *PCA) ComponentsFor(ratio float64) int {
+	explained := 0.0
+	for i, r := range p.ExplainedRatio {
+		explained += r
+		if explained >= ratio-1e-12 {
+			return i + 1
+		}
+	}
+	return len(p.ExplainedRatio)
+}
+
+// checkComponents returns an error if k isn't a valid number of components
+func (p *PCA) checkComponents(k int) error {
+	if k < 1 || k > len(p.Components) {
+		return errors.New("number of components must be between 1 and the " +
+			"number of features")
+	}
+	return nil
+}
+
+// Project returns a point whose variables are the coordinates of the centered
+// features of point along the first k components, keeping its label
+func (p *PCA) Project(point *MlDataPoint, k int) (*MlDataPoint, error) {
+	if err := p.checkComponents(k); err != nil {
+		return nil, err
+	}
+	if len(point.Variables) != len(p.Means) {
+		return nil, ErrFieldsCount
+	}
+	centered := make([]float64, len(p.Means))
+	for j, v := range point.Variables {
+		centered[j] = v - p.Means[j]
+	}
+	projected := &MlDataPoint{Label: point.Label,
+		Variables: make([]float64, k)}
+	for i := 0; i < k; i++ {
+		projected.Variables[i] = dot(p.Components[i], centered)
+	}
+	return projected, nil
+}
+
+// ProjectPoints projects points along the first k components, see Project
+func (p *PCA) ProjectPoints(points []MlDataPoint, k int) ([]MlDataPoint,
+	error) {
+	projected := make([]MlDataPoint, len(points))
+	for i := range points {
+		point, err := p.Project(&points[i], k)
+		if err != nil {
+			return nil, err
+		}
+		projected[i] = *point
+	}
+	return projected, nil
+}
+
+// ProjectMoments returns the XᵀX and Xᵀy moments of points projected along
+// the first k components, computed from the moments of the points, so that
+// a linear model of the components is fitted from aggregates only
+func (p *PCA) ProjectMoments(xtx [][]float64, xty []float64, k int) (
+	[][]float64, []float64, error) {
+	if err := p.checkComponents(k); err != nil {
+		return nil, nil, err
+	}
+	d := len(p.Means)
+	if len(xtx) != d+1 || len(xty) != d+1 {
+		return nil, nil, ErrFieldsCount
+	}
+	for _, row := range xtx {
+		if len(row) != d+1 {
+			return nil, nil, ErrFieldsCount
+		}
+	}
+	n := xtx[0][0]
+	// The moments of the centered features
+	centered := newMatrix(d, d)
+	centeredY := make([]float64, d)
+	for j := 0; j < d; j++ {
+		for l := 0; l < d; l++ {
+			centered[j][l] = xtx[j+1][l+1] - n*p.Means[j]*p.Means[l]
+		}
+		centeredY[j] = xty[j+1] - p.Means[j]*xty[0]
+	}
+	// The components are centered, so they sum to 0
+	projected := newMatrix(k+1, k+1)
+	projectedY := make([]float64, k+1)
+	projected[0][0], projectedY[0] = n, xty[0]
+	for a := 0; a < k; a++ {
+		projectedY[a+1] = dot(p.Components[a], centeredY)
+		for b := 0; b < k; b++ {
+			for j := 0; j < d; j++ {
+				projected[a+1][b+1] += p.Components[a][j] *
+					dot(centered[j], p.Components[b])
+			}
+		}
+	}
+	return projected, projectedY, nil
+}
+
+// symmetricEigen returns the eigenvalues of a symmetric matrix and its
+// eigenvectors, in the columns of the second matrix, by the cyclic Jacobi
+// method
+func symmetricEigen(m [][]float64) ([]float64, [][]float64) {
+	n := len(m)
+	a := newMatrix(n, n)
+	v := newMatrix(n, n)
+	for i := range m {
+		copy(a[i], m[i])
+		v[i][i] = 1
+	}
+	for sweep := 0; sweep < 100; sweep++ {
+		off, norm := 0.0, 0.0
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if i != j {
+					off += a[i][j] * a[i][j]
+				}
+				norm += a[i][j] * a[i][j]
+			}
+		}
+		if off <= 1e-24*norm {
+			break
+		}
+		for p := 0; p < n; p++ {
+			for q := p + 1; q < n; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// The rotation zeroing a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < n; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p], a[k][q] = c*akp-s*akq, s*akp+c*akq
+				}
+				for k := 0; k < n; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k], a[q][k] = c*apk-s*aqk, s*apk+c*aqk
+				}
+				for k := 0; k < n; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p], v[k][q] = c*vkp-s*vkq, s*vkp+c*vkq
+				}
+			}
+		}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = a[i][i]
+	}
+	return values, v
+}
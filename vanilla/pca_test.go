@@ -0,0 +1,91 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPCA(t *testing.T) {
+	// The points spread along (1, 1, 0) much more than along the other axes
+	r := rand.New(rand.NewSource(7))
+	points := make([]vanilla.MlDataPoint, 500)
+	for i := range points {
+		u, v, w := 10*r.NormFloat64(), r.NormFloat64(), 0.1*r.NormFloat64()
+		points[i] = vanilla.MlDataPoint{Label: 2*u + 1,
+			Variables: []float64{5 + u + v, -3 + u - v, w}}
+	}
+	p, err := vanilla.PCAFromPoints(points, []string{"a", "b", "c"})
+	require.Nil(t, err)
+	require.Equal(t, 500, p.Count)
+	require.InDelta(t, 5, p.Means[0], 1)
+	require.InDelta(t, 1/math.Sqrt2, math.Abs(p.Components[0][0]), 0.01)
+	require.InDelta(t, 1/math.Sqrt2, math.Abs(p.Components[0][1]), 0.01)
+	require.InDelta(t, 1, math.Abs(p.Components[2][2]), 0.01)
+	require.True(t, p.Variances[0] > p.Variances[1] &&
+		p.Variances[1] > p.Variances[2])
+	require.True(t, p.ExplainedRatio[0] > 0.95)
+	require.Equal(t, 1, p.ComponentsFor(0.9))
+	require.Equal(t, 2, p.ComponentsFor(0.9999))
+
+	// Every component is an eigenvector of the covariance
+	for i, component := range p.Components {
+		for j := range component {
+			product := 0.0
+			for k := range component {
+				product += p.Covariance[j][k] * component[k]
+			}
+			require.InDelta(t, p.Variances[i]*component[j], product, 1e-6)
+		}
+	}
+
+	// The variance of the projected points is the variance of the components
+	projected, err := p.ProjectPoints(points, 2)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(projected[0].Variables))
+	require.Equal(t, points[0].Label, projected[0].Label)
+	sum := 0.0
+	for _, q := range projected {
+		sum += q.Variables[0] * q.Variables[0]
+	}
+	require.InDelta(t, p.Variances[0], sum/499, 1e-6)
+	_, err = p.Project(&points[0], 4)
+	require.NotNil(t, err)
+
+	// A regression of the components is fitted from the moments alone
+	a := vanilla.NewGramAccumulator()
+	for i := range points {
+		require.Nil(t, a.Add(&points[i]))
+	}
+	fromMoments, err := vanilla.PCAFromMoments(a.XtX, nil)
+	require.Nil(t, err)
+	require.InDelta(t, p.Variances[1], fromMoments.Variances[1], 1e-9)
+	xtx, xty, err := p.ProjectMoments(a.XtX, a.XtY, 2)
+	require.Nil(t, err)
+	m, err := vanilla.SolveNormalEquations(xtx, xty, nil)
+	require.Nil(t, err)
+	expected, err := train(t, "ols", nil, projected)
+	require.Nil(t, err)
+	for j, c := range expected.Coefficients {
+		require.InDelta(t, c, m.Coefficients[j], 1e-6)
+	}
+	ragged := append([][]float64{}, a.XtX...)
+	ragged[2] = ragged[2][:2]
+	_, _, err = p.ProjectMoments(ragged, a.XtY, 2)
+	require.Equal(t, vanilla.ErrFieldsCount, err)
+
+	dir, err := ioutil.TempDir("", "pca")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "pca.json")
+	require.Nil(t, p.Save(fileName))
+	loaded, err := vanilla.LoadPCA(fileName)
+	require.Nil(t, err)
+	require.Equal(t, p, loaded)
+}
@@ -0,0 +1,210 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// ModelFileVersion is the version of the format of the files written by
+// SavedModel.Save
+const ModelFileVersion = 1
+
+// ModelMetadata describes how a model was trained
+type ModelMetadata struct {
+	// Dataset is the file the points were loaded from
+	Dataset string
+	// WriteInstances are the hex ids of the Calypso write instances whose
+	// points were read to train the model
+	WriteInstances []string `json:",omitempty"`
+	TrainingPoints int
+	Trained        time.Time
+	// Evaluation is the evaluation of the model on held-out points, if any
+	Evaluation *Evaluation `json:",omitempty"`
+}
+
+// SavedModel is a trained model with everything needed to use it on new
+// points
+type SavedModel struct {
+	// Trainer is the name of the registered trainer of the model, which
+	// decodes it
+	Trainer string
+	Params  Params `json:",omitempty"`
+	Columns Columns
+	// Scaler, when set, scales the features of the points before they are
+	// given to the model, which was trained on scaled features
+	Scaler *Scaler `json:",omitempty"`
+	// Schema and Missing, when set, are how the columns of the training
+	// dataset were encoded and how its missing cells were filled, with the
+	// statistics of the training dataset, so that new points are loaded as
+	// the training points were
+	Schema   *Schema        `json:",omitempty"`
+	Missing  *MissingPolicy `json:",omitempty"`
+	Metadata ModelMetadata
+	Model    Model `json:"-"`
+}
+
+// savedModelFile is the json encoding of a SavedModel
+type savedModelFile struct {
+	Version int
+	*SavedModel
+	// Model is the encoding of the model by its MarshalBinary method
+	Model []byte
+}
+
+// LoadModel reads a model saved by SavedModel.Save
+func LoadModel(fileName string) (*SavedModel, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeSavedModel(data)
+}
+
+// DecodeSavedModel decodes a model encoded by SavedModel.Encode
+func DecodeSavedModel(data []byte) (*SavedModel, error) {
+	file := &savedModelFile{SavedModel: &SavedModel{}}
+	err := json.Unmarshal(data, file)
+	if err != nil {
+		return nil, errors.New("couldn't decode model file: " + err.Error())
+	}
+	if file.Version != ModelFileVersion {
+		return nil, fmt.Errorf("model file has version %d, expected %d",
+			file.Version, ModelFileVersion)
+	}
+	file.SavedModel.Model, err = DecodeModel(file.Trainer, file.Model)
+	if err != nil {
+		return nil, err
+	}
+	return file.SavedModel, nil
+}
+
+// Encode encodes the model as the json of its file
+func (s *SavedModel) Encode() ([]byte, error) {
+	model, err := s.Model.MarshalBinary()
+	if err != nil {
+		return nil, errors.New("couldn't encode model: " + err.Error())
+	}
+	data, err := json.MarshalIndent(&savedModelFile{ModelFileVersion, s,
+		model}, "", "  ")
+	if err != nil {
+		return nil, errors.New("couldn't encode model file: " + err.Error())
+	}
+	return data, nil
+}
+
+// Save writes the model as json to a file
+func (s *SavedModel) Save(fileName string) error {
+	data, err := s.Encode()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// Describe returns the description of the model, in the original units of
+// the features when the model can be unscaled
+func (s *SavedModel) Describe() string {
+	if s.Scaler == nil {
+		return s.Model.Describe()
+	}
+	unscaled, err := s.Scaler.UnscaleModel(s.Model)
+	if err != nil {
+		return s.Model.Describe() + " (on scaled features)"
+	}
+	return unscaled.Describe()
+}
+
+// Summary returns the summary of the model, such as the inference of the
+// coefficients of a linear model, in the original units of the features when
+// the model can be unscaled, or an empty string if the model has none
+func (s *SavedModel) Summary() string {
+	model := s.Model
+	if s.Scaler != nil {
+		unscaled, err := s.Scaler.UnscaleModel(s.Model)
+		if err != nil {
+			return ""
+		}
+		model = unscaled
+	}
+	if summarizer, ok := model.(interface{ Summary() string }); ok {
+		return summarizer.Summary()
+	}
+	return ""
+}
+
+// Predict returns the label predicted for a point, whose variables are in
+// their original units
+func (s *SavedModel) Predict(point *MlDataPoint) (float64, error) {
+	if s.Scaler == nil {
+		return s.Model.Predict(point.Variables)
+	}
+	scaled := &MlDataPoint{Variables: append([]float64{}, point.Variables...)}
+	if err := s.Scaler.Scale(scaled); err != nil {
+		return 0, err
+	}
+	return s.Model.Predict(scaled.Variables)
+}
+
+// Prediction is the label predicted for a row of a dataset file
+type Prediction struct {
+	// Row is the record number in the file, the header being row 1
+	Row   int
+	Label float64
+}
+
+// PredictFile returns the labels predicted for the rows of a dataset file
+// loaded with opts, which may be nil, and with the Schema and Missing of the
+// model, which replace those of opts. The rows skipped in lenient mode or
+// dropped because of missing cells have no prediction. The features loaded
+// must be the features of the model, and unlabeled files are loaded with
+// LoadOptions.Unlabeled.
+func (s *SavedModel) PredictFile(fileName string, opts *LoadOptions) (
+	[]Prediction, error) {
+	var loading LoadOptions
+	if opts != nil {
+		loading = *opts
+	}
+	if s.Schema != nil {
+		loading.Schema = s.Schema
+	}
+	if s.Missing != nil {
+		loading.Missing = s.Missing
+	}
+	// The points are scaled by Predict
+	loading.Scaler = nil
+	open, err := recordOpener(fileName, &loading)
+	if err != nil {
+		return nil, err
+	}
+	source, err := newRecordSource(fileName, &loading, open)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	features := source.Columns().Features
+	if err := (&Scaler{Features: s.Columns.Features}).check(
+		features); err != nil {
+		return nil, &LoadError{File: fileName,
+			Err: errors.New("dataset features don't match the model")}
+	}
+	var predictions []Prediction
+	for {
+		point, err := source.Next()
+		if err == io.EOF {
+			return predictions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		predicted, err := s.Predict(point)
+		if err != nil {
+			return nil, err
+		}
+		predictions = append(predictions, Prediction{Row: source.row,
+			Label: predicted})
+	}
+}
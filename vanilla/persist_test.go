@@ -0,0 +1,155 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedModel(t *testing.T) {
+	d := linearDataset(t)
+	columns := &vanilla.Columns{Label: "y",
+		Features: []string{"x1", "x2", "x3"}}
+	scaler, err := vanilla.FitScaler(vanilla.ZScore, columns.Features,
+		d.Points)
+	require.Nil(t, err)
+	scaled := make([]vanilla.MlDataPoint, len(d.Points))
+	for i, p := range d.Points {
+		scaled[i] = vanilla.MlDataPoint{Label: p.Label,
+			Variables: append([]float64{}, p.Variables...)}
+	}
+	require.Nil(t, scaler.ScalePoints(scaled))
+	trainer, err := vanilla.NewTrainer("ols", nil)
+	require.Nil(t, err)
+	model, err := trainer.Train(scaled, columns)
+	require.Nil(t, err)
+
+	dir, err := ioutil.TempDir("", "model")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "model.json")
+	saved := &vanilla.SavedModel{Trainer: "ols", Columns: *columns,
+		Scaler: scaler, Model: model,
+		Metadata: vanilla.ModelMetadata{Dataset: "synthetic",
+			TrainingPoints: len(scaled)}}
+	require.Nil(t, saved.Save(fileName))
+	loaded, err := vanilla.LoadModel(fileName)
+	require.Nil(t, err)
+	require.Equal(t, saved, loaded)
+	require.Contains(t, loaded.Describe(), "3.0")
+
+	// The encoding is what is written to Calypso when the model is published
+	data, err := saved.Encode()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeSavedModel(data)
+	require.Nil(t, err)
+	require.Equal(t, saved, decoded)
+	_, err = vanilla.DecodeSavedModel(data[1:])
+	require.NotNil(t, err)
+
+	// The points are predicted in the original units of their features
+	predicted, err := loaded.Predict(&d.Points[0])
+	require.Nil(t, err)
+	require.InDelta(t, d.Points[0].Label, predicted, 2)
+	expected, err := model.Predict(scaled[0].Variables)
+	require.Nil(t, err)
+	require.InDelta(t, expected, predicted, 1e-9)
+
+	predictions, err := loaded.PredictFile("tests/unlabeled.csv",
+		&vanilla.LoadOptions{Unlabeled: true})
+	require.Nil(t, err)
+	require.Len(t, predictions, 3)
+	require.InDelta(t, 2+3-2+0.03, predictions[0].Label, 0.5)
+	require.InDelta(t, 2, predictions[2].Label, 0.5)
+	require.Equal(t, 4, predictions[2].Row)
+
+	_, err = loaded.PredictFile("tests/test2.csv", nil)
+	require.NotNil(t, err)
+
+	require.Nil(t, ioutil.WriteFile(fileName, []byte(`{"Version": 2}`),
+		0644))
+	_, err = vanilla.LoadModel(fileName)
+	require.NotNil(t, err)
+}
+
+func TestUnlabeledDataset(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/unlabeled.csv",
+		&vanilla.LoadOptions{Unlabeled: true})
+	require.Nil(t, err)
+	require.Equal(t, []string{"x1", "x2", "x3"}, dataset.Features)
+	points := dataset.MlDataPoints("test")
+	require.Equal(t, []float64{4, 5, 6}, points[1].Variables)
+
+	_, err = vanilla.LoadDataset("tests/unlabeled.csv",
+		&vanilla.LoadOptions{Unlabeled: true, Label: "x3"})
+	require.NotNil(t, err)
+}
+
+func TestSavedModelLoading(t *testing.T) {
+	dir, err := ioutil.TempDir("", "model")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "model.json")
+
+	// The mean of the training dataset fills the missing cells of the points
+	// predicted, rather than the mean of their file
+	s := &vanilla.MlSimulation{Dataset: "tests/incomplete.csv",
+		Missing: "mean", MissingTokens: "NA", Lenient: true,
+		Trainer: "ridge", TrainerParams: "lambda=1"}
+	opts, err := s.LoadOptions()
+	require.Nil(t, err)
+	require.Equal(t, vanilla.FillExternal, opts.Missing.Strategy)
+	dataset, err := vanilla.LoadDataset(s.Dataset, opts)
+	require.Nil(t, err)
+	saved, err := s.TrainModel(dataset.MlDataPoints("test"),
+		&dataset.Columns, opts)
+	require.Nil(t, err)
+	require.Equal(t, opts.Missing, saved.Missing)
+	require.Nil(t, saved.Save(fileName))
+	loaded, err := vanilla.LoadModel(fileName)
+	require.Nil(t, err)
+	predictions, err := loaded.PredictFile(s.Dataset,
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	require.Len(t, predictions, 3)
+	first := &vanilla.MlDataPoint{Variables: []float64{1, 2,
+		opts.Missing.Stats["field3"]}}
+	expected, err := loaded.Predict(first)
+	require.Nil(t, err)
+	require.InDelta(t, expected, predictions[0].Label, 1e-9)
+	// The row missing its label isn't predicted
+	require.Equal(t, []int{2, 3, 5}, []int{predictions[0].Row,
+		predictions[1].Row, predictions[2].Row})
+
+	// The categories are encoded by the schema of the training dataset
+	schema, err := vanilla.LoadSchema("tests/categorical.json")
+	require.Nil(t, err)
+	s = &vanilla.MlSimulation{Dataset: "tests/categorical.csv",
+		Lenient: true, Trainer: "ridge", TrainerParams: "lambda=1"}
+	opts = &vanilla.LoadOptions{Schema: schema, Lenient: true}
+	dataset, err = vanilla.LoadDataset(s.Dataset, opts)
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+	saved, err = s.TrainModel(points, &dataset.Columns, opts)
+	require.Nil(t, err)
+	require.Nil(t, saved.Save(fileName))
+	loaded, err = vanilla.LoadModel(fileName)
+	require.Nil(t, err)
+	require.Equal(t, schema, loaded.Schema)
+	predictions, err = loaded.PredictFile(s.Dataset,
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	require.Len(t, predictions, len(points))
+	for i := range points {
+		expected, err := loaded.Predict(&points[i])
+		require.Nil(t, err)
+		require.InDelta(t, expected, predictions[i].Label, 1e-9)
+	}
+	loaded.Schema = nil
+	_, err = loaded.PredictFile(s.Dataset, &vanilla.LoadOptions{Lenient: true})
+	require.NotNil(t, err)
+}
@@ -0,0 +1,332 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dedis/cothority"
+	"github.com/dedis/cothority/byzcoin"
+	"github.com/dedis/cothority/calypso"
+	"github.com/dedis/cothority/darc"
+	"github.com/dedis/onet/log"
+	"github.com/dedis/onet/simul/monitor"
+)
+
+// pipeline are the points of a dataset written to Calypso by a provider each,
+// which the consumer reads to train a model
+type pipeline struct {
+	*MlSimulation
+	consumer    darc.Signer
+	darcs       []*darc.Darc
+	providers   []darc.Signer
+	writeInsts  []byzcoin.InstanceID
+	writeProofs []*byzcoin.Proof
+	readInsts   []byzcoin.InstanceID
+	readProofs  []*byzcoin.Proof
+	// reads are the indices of the points the consumer read, in order
+	reads []int
+}
+
+// RunPipeline runs the simulated pipeline on the ledger created by the
+// simulation: every point of Dataset is written to Calypso by a provider of
+// its own, then the consumer reads the points, trains the model selected by
+// Trainer, saves it to ModelFile if set and publishes it if Publish is set
+func (s *MlSimulation) RunPipeline(consumer darc.Signer) error {
+	opts, err := s.LoadOptions()
+	if err != nil {
+		return err
+	}
+	prepareTime := monitor.NewTimeMeasure("prepare")
+	p := &pipeline{MlSimulation: s, consumer: consumer}
+	columns, err := p.write(opts)
+	if err != nil {
+		return err
+	}
+	prepareTime.Record()
+
+	pipelineTime := monitor.NewTimeMeasure("pipeline")
+	var model *SavedModel
+	if s.Online {
+		model, err = p.trainOnline(&columns, opts)
+	} else {
+		model, err = p.train(&columns, opts)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("Training finished, model is: %s", model.Describe())
+	if s.SearchSpace != "" {
+		log.Printf("Best searched parameters: %s", model.Params)
+	}
+	if summary := model.Summary(); summary != "" {
+		log.Printf("Model coefficients:\n%s", summary)
+	}
+	if model.Metadata.Evaluation != nil {
+		log.Printf("Model evaluation on held-out points: %s",
+			model.Metadata.Evaluation)
+	}
+	for _, i := range p.reads {
+		id := p.writeInsts[i]
+		model.Metadata.WriteInstances = append(
+			model.Metadata.WriteInstances, fmt.Sprintf("%x", id[:]))
+	}
+	if s.ModelFile != "" {
+		err = model.Save(s.ModelFile)
+		if err != nil {
+			return errors.New("couldn't save model: " + err.Error())
+		}
+	}
+	pipelineTime.Record()
+
+	if s.Publish {
+		return p.publish(model)
+	}
+	return nil
+}
+
+// write writes every point of Dataset loaded with opts to Calypso, under a
+// darc of a new provider readable by the consumer, and waits for the writes
+// to be executed. It returns the columns of the points.
+func (p *pipeline) write(opts *LoadOptions) (Columns, error) {
+	log.Print("Reading dataset from ", p.Dataset)
+	source, err := OpenDataSource(p.Dataset, opts)
+	if err != nil {
+		return Columns{}, errors.New("couldn't read dataset: " + err.Error())
+	}
+	defer source.Close()
+	consumerID := p.consumer.Identity()
+	imputed := 0
+	for i := 0; ; i++ {
+		point, err := source.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Columns{}, errors.New("couldn't read dataset: " +
+				err.Error())
+		}
+		imputed += len(source.Imputed())
+		provider := darc.NewSignerEd25519(nil, nil)
+		secret, d, err := AssociateProvider(provider.Identity(), point, i,
+			&consumerID)
+		if err != nil {
+			return Columns{}, errors.New("couldn't associate data to " +
+				"provider: " + err.Error())
+		}
+		_, err = p.Client.SpawnDarc(p.Admin, uint64(i+1), p.Gm.GenesisDarc,
+			*d, 4)
+		if err != nil {
+			return Columns{}, errors.New("couldn't spawn provider darc: " +
+				err.Error())
+		}
+		log.Printf("Darc %d spawned", i)
+		write := calypso.NewWrite(cothority.Suite, p.LtsReply.LTSID,
+			d.GetBaseID(), p.LtsReply.X, secret)
+		reply, err := p.Client.AddWrite(write, provider, 1, *d, 0)
+		if err != nil {
+			return Columns{}, errors.New("couldn't spawn write instance: " +
+				err.Error())
+		}
+		p.darcs = append(p.darcs, d)
+		p.providers = append(p.providers, provider)
+		p.writeInsts = append(p.writeInsts, reply.InstanceID)
+	}
+	for _, rejected := range source.Rejected() {
+		log.Warn("Skipped row: ", rejected)
+	}
+	log.Print("Dataset has ", len(p.darcs), " instances, ", imputed,
+		" cells were imputed")
+
+	p.writeProofs = make([]*byzcoin.Proof, len(p.darcs))
+	p.readInsts = make([]byzcoin.InstanceID, len(p.darcs))
+	p.readProofs = make([]*byzcoin.Proof, len(p.darcs))
+	for i := range p.writeInsts {
+		p.writeProofs[i], err = p.Client.WaitProof(p.writeInsts[i],
+			p.Gm.BlockInterval, nil)
+		if err != nil {
+			return Columns{}, errors.New("couldn't get write proof: " +
+				err.Error())
+		}
+	}
+	return source.Columns(), nil
+}
+
+// spawnRead spawns the read instance of the i-th point by the consumer
+func (p *pipeline) spawnRead(i int) error {
+	readSpawnTime := monitor.NewTimeMeasure("read_spawn")
+	reply, err := p.Client.AddRead(p.writeProofs[i], p.consumer,
+		uint64(len(p.reads)+1), *p.darcs[i], 0)
+	if err != nil {
+		return errors.New("couldn't spawn read instance: " + err.Error())
+	}
+	p.readInsts[i] = reply.InstanceID
+	p.reads = append(p.reads, i)
+	readSpawnTime.Record()
+	return nil
+}
+
+// waitRead waits for the read instance of the i-th point to be executed
+func (p *pipeline) waitRead(i int) error {
+	readProofTime := monitor.NewTimeMeasure("read_proof")
+	prf, err := p.Client.WaitProof(p.readInsts[i], p.Gm.BlockInterval, nil)
+	if err != nil {
+		return errors.New("couldn't get read proof: " + err.Error())
+	}
+	p.readProofs[i] = prf
+	readProofTime.Record()
+	return nil
+}
+
+// decrypt decrypts the i-th point once it was read
+func (p *pipeline) decrypt(i int) (*MlDataPoint, error) {
+	decryptTime := monitor.NewTimeMeasure("decrypt")
+	reply, err := p.Client.DecryptKey(&calypso.DecryptKey{
+		Read: *p.readProofs[i], Write: *p.writeProofs[i]})
+	if err != nil {
+		return nil, errors.New("couldn't decrypt key: " + err.Error())
+	}
+	if !reply.X.Equal(p.LtsReply.X) {
+		return nil, errors.New("LTS didn't match")
+	}
+	data, err := calypso.DecodeKey(cothority.Suite, p.LtsReply.X, reply.Cs,
+		reply.XhatEnc, p.consumer.Ed25519.Secret)
+	if err != nil {
+		return nil, errors.New("couldn't decode data point: " + err.Error())
+	}
+	point := &MlDataPoint{}
+	err = json.Unmarshal(data, point)
+	if err != nil {
+		return nil, errors.New("couldn't cast data point from binary: " +
+			err.Error())
+	}
+	decryptTime.Record()
+	return point, nil
+}
+
+// trainOnline reads and learns every point before reading the next one, and
+// stops reading once the training is done, see TrainOnline
+func (p *pipeline) trainOnline(columns *Columns, opts *LoadOptions) (
+	*SavedModel, error) {
+	online, err := p.NewOnlineTrainer(columns)
+	if err != nil {
+		return nil, errors.New("couldn't train model: " + err.Error())
+	}
+	err = p.TrainOnline(online, len(p.darcs),
+		func(i int) (*MlDataPoint, error) {
+			if err := p.spawnRead(i); err != nil {
+				return nil, err
+			}
+			if err := p.waitRead(i); err != nil {
+				return nil, err
+			}
+			return p.decrypt(i)
+		})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Online training read %d of %d points", len(p.reads),
+		len(p.darcs))
+	model, err := p.OnlineModel(online, opts)
+	if err != nil {
+		return nil, errors.New("couldn't train model: " + err.Error())
+	}
+	return model, nil
+}
+
+// train reads every point and trains the model on them. When streaming,
+// every point is folded into an accumulator and dropped as soon as it is
+// decrypted.
+func (p *pipeline) train(columns *Columns, opts *LoadOptions) (*SavedModel,
+	error) {
+	// The streamed training is checked before any point is read
+	var accumulator *GramAccumulator
+	if p.Streaming {
+		var err error
+		accumulator, err = p.NewStreamingAccumulator()
+		if err != nil {
+			return nil, errors.New("couldn't train model: " + err.Error())
+		}
+	}
+	for i := range p.darcs {
+		if err := p.spawnRead(i); err != nil {
+			return nil, err
+		}
+	}
+	for i := range p.darcs {
+		if err := p.waitRead(i); err != nil {
+			return nil, err
+		}
+	}
+	var points []MlDataPoint
+	for i := range p.darcs {
+		point, err := p.decrypt(i)
+		if err != nil {
+			return nil, err
+		}
+		if accumulator == nil {
+			points = append(points, *point)
+		} else if err := accumulator.Add(point); err != nil {
+			return nil, errors.New("couldn't accumulate data point: " +
+				err.Error())
+		}
+	}
+
+	var model *SavedModel
+	var err error
+	if accumulator != nil {
+		log.Printf("Accumulated %d points, label counts: %v",
+			accumulator.Count(), accumulator.LabelCounts)
+		model, err = p.StreamedModel(accumulator, columns, opts)
+	} else {
+		model, err = p.TrainModel(points, columns, opts)
+	}
+	if err != nil {
+		return nil, errors.New("couldn't train model: " + err.Error())
+	}
+	return model, nil
+}
+
+// publish publishes the model, readable by the providers of the points it
+// was trained on and/or an ethics board as set by ModelReaders, and reads it
+// back as one of them
+func (p *pipeline) publish(model *SavedModel) error {
+	providersRead, boardRead, err := p.ModelReaderGroups()
+	if err != nil {
+		return err
+	}
+	var readers []darc.Signer
+	if providersRead {
+		for _, i := range p.reads {
+			readers = append(readers, p.providers[i])
+		}
+	}
+	if boardRead {
+		readers = append(readers, darc.NewSignerEd25519(nil, nil))
+	}
+	publishTime := monitor.NewTimeMeasure("publish")
+	published, err := p.PublishModel(model, p.consumer,
+		uint64(len(p.reads)+1), uint64(len(p.darcs)+1),
+		GetIdentitiesFromSigners(readers))
+	if err != nil {
+		return errors.New("couldn't publish model: " + err.Error())
+	}
+	publishTime.Record()
+	log.Printf("Model published in write instance %x", published.Write[:])
+
+	// A reader accesses the model, which leaves a read instance on the
+	// ledger. The providers already signed their write, the board didn't
+	// sign anything.
+	modelReadTime := monitor.NewTimeMeasure("model_read")
+	reader, readerCtr := readers[0], uint64(2)
+	if boardRead {
+		reader, readerCtr = readers[len(readers)-1], uint64(1)
+	}
+	_, err = p.ReadModel(published, reader, readerCtr)
+	if err != nil {
+		return errors.New("couldn't read published model: " + err.Error())
+	}
+	modelReadTime.Record()
+	return nil
+}
@@ -0,0 +1,357 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// HistogramBin counts the values of a column in [Low, High), the last bin
+// including High
+type HistogramBin struct {
+	Low   float64
+	High  float64
+	Count int
+}
+
+// ColumnProfile summarizes the cells of a column
+type ColumnProfile struct {
+	Name string
+	// Count is the number of present cells, Missing the number of missing ones
+	Count    int
+	Missing  int
+	Distinct int
+	// Numeric tells if all the present cells are finite numbers, the
+	// statistics below are only computed for numeric columns
+	Numeric bool
+	Min     float64
+	Max     float64
+	Mean    float64
+	StdDev  float64
+	// Decimals is the highest number of significant decimals of the cells
+	Decimals  int
+	Histogram []HistogramBin `json:",omitempty"`
+	// Categories count the values of non-numeric columns
+	Categories map[string]int `json:",omitempty"`
+}
+
+// Profile summarizes the columns of a dataset
+type Profile struct {
+	File string
+	Rows int
+	// Malformed is the number of rows whose number of cells doesn't match
+	// the header, which aren't profiled
+	Malformed int
+	Columns   []ColumnProfile
+}
+
+// ProfileDataset profiles every column of a dataset file. Only the Format and
+// the missing tokens of opts are used, and opts may be nil. Numeric columns
+// get a histogram of the given number of bins.
+func ProfileDataset(fileName string, opts *LoadOptions, bins int) (*Profile,
+	error) {
+	if bins < 1 {
+		return nil, errors.New("histograms need at least one bin")
+	}
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	open, err := recordOpener(fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	records, err := open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+	headers := records.Headers()
+
+	profile := &Profile{File: fileName,
+		Columns: make([]ColumnProfile, len(headers))}
+	values := make([][]float64, len(headers))
+	distinct := make([]map[string]int, len(headers))
+	for j, h := range headers {
+		profile.Columns[j] = ColumnProfile{Name: h, Numeric: true}
+		distinct[j] = make(map[string]int)
+	}
+	for {
+		record, row, err := records.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, &LoadError{File: fileName, Row: row, Err: err}
+		}
+		if len(record) != len(headers) {
+			profile.Malformed++
+			continue
+		}
+		profile.Rows++
+		for j, cell := range record {
+			c := &profile.Columns[j]
+			if opts.Missing.isMissing(cell) {
+				c.Missing++
+				continue
+			}
+			cell = strings.TrimSpace(cell)
+			c.Count++
+			distinct[j][cell]++
+			v, err := strconv.ParseFloat(cell, 64)
+			// NaN and infinite cells have no range to encode for Prio
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				c.Numeric = false
+				continue
+			}
+			values[j] = append(values[j], v)
+			if d := decimals(cell); d > c.Decimals {
+				c.Decimals = d
+			}
+		}
+	}
+
+	for j := range profile.Columns {
+		c := &profile.Columns[j]
+		c.Distinct = len(distinct[j])
+		if c.Count == 0 {
+			c.Numeric = false
+		}
+		if c.Numeric {
+			c.summarize(values[j], bins)
+		} else {
+			c.Decimals = 0
+			c.Categories = distinct[j]
+		}
+	}
+	return profile, nil
+}
+
+// decimals returns the number of significant decimals of a number
+func decimals(cell string) int {
+	cell = strings.ToLower(cell)
+	exponent := 0
+	if i := strings.Index(cell, "e"); i >= 0 {
+		exponent, _ = strconv.Atoi(cell[i+1:])
+		cell = cell[:i]
+	}
+	d := 0
+	if i := strings.Index(cell, "."); i >= 0 {
+		d = len(strings.TrimRight(cell[i+1:], "0"))
+	}
+	if d -= exponent; d < 0 {
+		return 0
+	}
+	return d
+}
+
+// summarize computes the statistics and the histogram of a numeric column
+func (c *ColumnProfile) summarize(values []float64, bins int) {
+	c.Min, c.Max = values[0], values[0]
+	for _, v := range values {
+		c.Min = math.Min(c.Min, v)
+		c.Max = math.Max(c.Max, v)
+	}
+	c.Mean = mean(values)
+	variance := 0.0
+	for _, v := range values {
+		variance += (v - c.Mean) * (v - c.Mean)
+	}
+	c.StdDev = math.Sqrt(variance / float64(len(values)))
+
+	width := (c.Max - c.Min) / float64(bins)
+	if width == 0 {
+		c.Histogram = []HistogramBin{{c.Min, c.Max, len(values)}}
+		return
+	}
+	c.Histogram = make([]HistogramBin, bins)
+	for b := range c.Histogram {
+		c.Histogram[b].Low = c.Min + float64(b)*width
+		c.Histogram[b].High = c.Min + float64(b+1)*width
+	}
+	c.Histogram[bins-1].High = c.Max
+	for _, v := range values {
+		b := int((v - c.Min) / width)
+		if b >= bins {
+			b = bins - 1
+		}
+		c.Histogram[b].Count++
+	}
+}
+
+// Column returns the profile of a column, or nil if the dataset has no such
+// column
+func (p *Profile) Column(name string) *ColumnProfile {
+	for i := range p.Columns {
+		if p.Columns[i].Name == name {
+			return &p.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Save writes the profile as json to a file
+func (p *Profile) Save(fileName string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode profile: " + err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// WriteText writes the profile as a human readable table
+func (p *Profile) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "%s: %d rows, %d malformed\n", p.File, p.Rows, p.Malformed)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "column\tcount\tmissing\tdistinct\tmin\tmax\tmean\tstddev")
+	for _, c := range p.Columns {
+		if c.Numeric {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%g\t%g\t%.4g\t%.4g\n", c.Name,
+				c.Count, c.Missing, c.Distinct, c.Min, c.Max, c.Mean, c.StdDev)
+		} else {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t-\t-\t-\t-\n", c.Name, c.Count,
+				c.Missing, c.Distinct)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, c := range p.Columns {
+		fmt.Fprintf(w, "\n%s\n", c.Name)
+		for _, b := range c.Histogram {
+			fmt.Fprintf(w, "  [%g, %g]\t%d\n", b.Low, b.High, b.Count)
+		}
+		var categories []string
+		for category := range c.Categories {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			fmt.Fprintf(w, "  %s\t%d\n", category, c.Categories[category])
+		}
+	}
+	return nil
+}
+
+// PrioSuggestion are the Prio linear regression settings fitting the ranges
+// of a dataset. Prio aggregates nonnegative integers, so a value v is sent as
+// round((v - Offset) * Scale), which fits in the bits of its column, see
+// Encode.
+type PrioSuggestion struct {
+	// Columns are the features followed by the label, in the order of
+	// LinRegBits
+	Columns    []string
+	Offsets    []float64
+	Scales     []float64
+	LinRegBits []int
+	// AccumulatorBits are the bits needed by the largest sum of products
+	// aggregated over all the rows, which must fit in the Prio field
+	AccumulatorBits int
+}
+
+// SuggestPrio suggests the Prio settings of the label and features of
+// columns, keeping at most maxDecimals decimals of the values
+func (p *Profile) SuggestPrio(columns Columns, maxDecimals int) (
+	*PrioSuggestion, error) {
+	s := &PrioSuggestion{Columns: append(append([]string{},
+		columns.Features...), columns.Label)}
+	for _, name := range s.Columns {
+		c := p.Column(name)
+		if c == nil {
+			return nil, fmt.Errorf("%v: %q", ErrUnknownColumn, name)
+		}
+		if !c.Numeric {
+			return nil, fmt.Errorf("column %q isn't numeric", name)
+		}
+		offset := math.Min(c.Min, 0)
+		d := c.Decimals
+		if d > maxDecimals {
+			d = maxDecimals
+		}
+		scale := math.Pow10(d)
+		width := bits.Len64(uint64(math.Ceil((c.Max - offset) * scale)))
+		if width == 0 {
+			width = 1
+		}
+		s.Offsets = append(s.Offsets, offset)
+		s.Scales = append(s.Scales, scale)
+		s.LinRegBits = append(s.LinRegBits, width)
+	}
+	// The sums of x_i * x_j and x_i * y over the rows are aggregated
+	widest := 0
+	for _, width := range s.LinRegBits {
+		if width > widest {
+			widest = width
+		}
+	}
+	s.AccumulatorBits = 2*widest + bits.Len(uint(p.Rows))
+	return s, nil
+}
+
+// Encode returns the integers sent to Prio for the features and the label of
+// a point, in the order of Columns, and an error if one doesn't fit in the
+// bits of its column
+func (s *PrioSuggestion) Encode(label float64, features []float64) (
+	[]int64, error) {
+	if len(features)+1 != len(s.Columns) {
+		return nil, fmt.Errorf("point has %d features, expected %d",
+			len(features), len(s.Columns)-1)
+	}
+	values := append(append([]float64{}, features...), label)
+	encoded := make([]int64, len(values))
+	for i, v := range values {
+		e := math.Round((v - s.Offsets[i]) * s.Scales[i])
+		if e < 0 || e >= math.Exp2(float64(s.LinRegBits[i])) {
+			return nil, fmt.Errorf("value %g of column %q doesn't fit in "+
+				"%d bits", v, s.Columns[i], s.LinRegBits[i])
+		}
+		encoded[i] = int64(e)
+	}
+	return encoded, nil
+}
+
+// LoadPrioSuggestion reads Prio settings saved by PrioSuggestion.Save
+func LoadPrioSuggestion(fileName string) (*PrioSuggestion, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	s := &PrioSuggestion{}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, errors.New("couldn't decode prio settings: " + err.Error())
+	}
+	n := len(s.Columns)
+	if n == 0 || len(s.Offsets) != n || len(s.Scales) != n ||
+		len(s.LinRegBits) != n {
+		return nil, errors.New("prio settings don't match their columns")
+	}
+	return s, nil
+}
+
+// Save writes the settings as json to a file, to be shared by the data
+// providers encoding their points and the consumer decoding the aggregates
+func (s *PrioSuggestion) Save(fileName string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode prio settings: " + err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// String returns the suggested linRegBits as they are written in a Prio
+// config file
+func (s *PrioSuggestion) String() string {
+	widths := make([]string, len(s.LinRegBits))
+	for i, width := range s.LinRegBits {
+		widths[i] = strconv.Itoa(width)
+	}
+	return "\"linRegBits\": [" + strings.Join(widths, ",") + "]"
+}
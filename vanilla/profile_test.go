@@ -0,0 +1,111 @@
+package vanilla_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProfileDataset(t *testing.T) {
+	profile, err := vanilla.ProfileDataset("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: &vanilla.MissingPolicy{
+			Tokens: []string{"NA"}}}, 2)
+	require.Nil(t, err)
+	require.Equal(t, 4, profile.Rows)
+
+	field2 := profile.Column("field2")
+	require.True(t, field2.Numeric)
+	require.Equal(t, 3, field2.Count)
+	require.Equal(t, 1, field2.Missing)
+	require.Equal(t, 3, field2.Distinct)
+	require.Equal(t, 2.0, field2.Min)
+	require.Equal(t, 10.0, field2.Max)
+	require.Equal(t, 6.0, field2.Mean)
+	require.Equal(t, []vanilla.HistogramBin{{2, 6, 1}, {6, 10, 2}},
+		field2.Histogram)
+	require.Equal(t, 2, profile.Column("label").Distinct)
+
+	// Without the NA token field2 isn't numeric
+	profile, err = vanilla.ProfileDataset("tests/incomplete.csv", nil, 2)
+	require.Nil(t, err)
+	field2 = profile.Column("field2")
+	require.False(t, field2.Numeric)
+	require.Equal(t, 1, field2.Categories["NA"])
+
+	var text bytes.Buffer
+	require.Nil(t, profile.WriteText(&text))
+	require.Contains(t, text.String(), "field3")
+
+	dir, err := ioutil.TempDir("", "profile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "profile.json")
+	require.Nil(t, profile.Save(fileName))
+	data, err := ioutil.ReadFile(fileName)
+	require.Nil(t, err)
+	loaded := &vanilla.Profile{}
+	require.Nil(t, json.Unmarshal(data, loaded))
+	require.Equal(t, profile, loaded)
+}
+
+func TestProfileNonFinite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "profile")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "nonfinite.csv")
+	require.Nil(t, ioutil.WriteFile(fileName,
+		[]byte("field1,field2,label\n1,2,0\nNaN,3,1\n2,+Inf,1\n"), 0644))
+	profile, err := vanilla.ProfileDataset(fileName, nil, 2)
+	require.Nil(t, err)
+	require.False(t, profile.Column("field1").Numeric)
+	require.False(t, profile.Column("field2").Numeric)
+	require.Equal(t, 0.0, profile.Column("field2").Max)
+	_, err = profile.SuggestPrio(vanilla.Columns{Label: "label",
+		Features: []string{"field1", "field2"}}, 0)
+	require.NotNil(t, err)
+}
+
+func TestSuggestPrio(t *testing.T) {
+	profile, err := vanilla.ProfileDataset("tests/test2.csv", nil, 4)
+	require.Nil(t, err)
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	s, err := profile.SuggestPrio(dataset.Columns, 3)
+	require.Nil(t, err)
+	require.Equal(t, []string{"field1", "field2", "field3", "label"},
+		s.Columns)
+	// 12.5 is sent as 125, which needs 7 bits
+	require.Equal(t, []float64{10, 1, 1, 1}, s.Scales)
+	require.Equal(t, []int{7, 4, 4, 2}, s.LinRegBits)
+	require.Equal(t, 2*7+3, s.AccumulatorBits)
+	require.Equal(t, `"linRegBits": [7,4,4,2]`, s.String())
+	encoded, err := s.Encode(1, []float64{12.5, 3, 4})
+	require.Nil(t, err)
+	require.Equal(t, []int64{125, 3, 4, 1}, encoded)
+	_, err = s.Encode(1, []float64{13, 3, 4})
+	require.NotNil(t, err)
+	_, err = s.Encode(1, []float64{-1, 3, 4})
+	require.NotNil(t, err)
+
+	dir, err := ioutil.TempDir("", "prio")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "prio.json")
+	require.Nil(t, s.Save(fileName))
+	loaded, err := vanilla.LoadPrioSuggestion(fileName)
+	require.Nil(t, err)
+	require.Equal(t, s, loaded)
+
+	s, err = profile.SuggestPrio(dataset.Columns, 0)
+	require.Nil(t, err)
+	require.Equal(t, 4, s.LinRegBits[0])
+
+	_, err = profile.SuggestPrio(vanilla.Columns{Label: "Classification"}, 3)
+	require.NotNil(t, err)
+}
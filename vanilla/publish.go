@@ -0,0 +1,126 @@
+package vanilla
+
+import (
+	"errors"
+
+	"github.com/dedis/cothority"
+	"github.com/dedis/cothority/byzcoin"
+	"github.com/dedis/cothority/calypso"
+	"github.com/dedis/cothority/darc"
+	"github.com/dedis/cothority/darc/expression"
+)
+
+// PublishedModel is a model written to Calypso by the consumer
+type PublishedModel struct {
+	// Darc controls the write, only its readers can read the model
+	Darc *darc.Darc
+	// Write is the write instance of the encrypted model
+	Write byzcoin.InstanceID
+	// Proof is the proof of the write instance, once it was executed
+	Proof *byzcoin.Proof
+}
+
+// ModelDarc returns the darc of a model written by the consumer and readable
+// by any of the readers, such as the data providers or an ethics board
+func ModelDarc(consumer darc.Identity, readers []darc.Identity,
+	desc string) (*darc.Darc, error) {
+	if len(readers) == 0 {
+		return nil, errors.New("a published model needs readers")
+	}
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{consumer},
+		[]darc.Identity{consumer}), []byte(desc))
+	d.Rules.AddRule(darc.Action("spawn:"+calypso.ContractWriteID),
+		expression.InitOrExpr(consumer.String()))
+	ids := make([]string, len(readers))
+	for i, reader := range readers {
+		ids[i] = reader.String()
+	}
+	d.Rules.AddRule(darc.Action("spawn:"+calypso.ContractReadID),
+		expression.InitOrExpr(ids...))
+	return d, nil
+}
+
+// ModelReaderGroups returns whether the providers and an ethics board may read
+// the published model, as set by ModelReaders
+func (s *MlSimulation) ModelReaderGroups() (providers bool, board bool,
+	err error) {
+	names := splitNames(s.ModelReaders)
+	if len(names) == 0 {
+		return true, false, nil
+	}
+	for _, name := range names {
+		switch name {
+		case "providers":
+			providers = true
+		case "board":
+			board = true
+		default:
+			return false, false, errors.New("unknown model reader: " + name)
+		}
+	}
+	return providers, board, nil
+}
+
+// PublishModel encrypts a model as a Calypso write under a darc, spawned by
+// the admin, readable by the readers. consumerCtr and adminCtr are the next
+// counters of the signers. Every read of the model is then a read instance
+// on the ledger, as are the reads of the data points.
+func (s *MlSimulation) PublishModel(model *SavedModel, consumer darc.Signer,
+	consumerCtr uint64, adminCtr uint64, readers []darc.Identity) (
+	*PublishedModel, error) {
+	data, err := model.Encode()
+	if err != nil {
+		return nil, err
+	}
+	d, err := ModelDarc(consumer.Identity(), readers, "Model")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.Client.SpawnDarc(s.Admin, adminCtr, s.Gm.GenesisDarc, *d, 4)
+	if err != nil {
+		return nil, errors.New("couldn't spawn model darc: " + err.Error())
+	}
+	write := calypso.NewWrite(cothority.Suite, s.LtsReply.LTSID,
+		d.GetBaseID(), s.LtsReply.X, data)
+	reply, err := s.Client.AddWrite(write, consumer, consumerCtr, *d, 0)
+	if err != nil {
+		return nil, errors.New("couldn't spawn model write instance: " +
+			err.Error())
+	}
+	prf, err := s.Client.WaitProof(reply.InstanceID, s.Gm.BlockInterval, nil)
+	if err != nil {
+		return nil, errors.New("couldn't get model write proof: " +
+			err.Error())
+	}
+	return &PublishedModel{Darc: d, Write: reply.InstanceID, Proof: prf}, nil
+}
+
+// ReadModel spawns a read instance of a published model, signed by reader
+// with its next counter readerCtr, and decrypts the model
+func (s *MlSimulation) ReadModel(published *PublishedModel,
+	reader darc.Signer, readerCtr uint64) (*SavedModel, error) {
+	reply, err := s.Client.AddRead(published.Proof, reader, readerCtr,
+		*published.Darc, 0)
+	if err != nil {
+		return nil, errors.New("couldn't spawn model read instance: " +
+			err.Error())
+	}
+	prf, err := s.Client.WaitProof(reply.InstanceID, s.Gm.BlockInterval, nil)
+	if err != nil {
+		return nil, errors.New("couldn't get model read proof: " + err.Error())
+	}
+	key, err := s.Client.DecryptKey(&calypso.DecryptKey{Read: *prf,
+		Write: *published.Proof})
+	if err != nil {
+		return nil, errors.New("couldn't decrypt key: " + err.Error())
+	}
+	if !key.X.Equal(s.LtsReply.X) {
+		return nil, errors.New("LTS didn't match")
+	}
+	data, err := calypso.DecodeKey(cothority.Suite, s.LtsReply.X, key.Cs,
+		key.XhatEnc, reader.Ed25519.Secret)
+	if err != nil {
+		return nil, errors.New("couldn't decode model: " + err.Error())
+	}
+	return DecodeSavedModel(data)
+}
@@ -0,0 +1,45 @@
+package vanilla_test
+
+import (
+	"testing"
+
+	"github.com/dedis/cothority/darc"
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelReaderGroups(t *testing.T) {
+	s := &vanilla.MlSimulation{}
+	providers, board, err := s.ModelReaderGroups()
+	require.Nil(t, err)
+	require.True(t, providers)
+	require.False(t, board)
+
+	s.ModelReaders = "board"
+	providers, board, err = s.ModelReaderGroups()
+	require.Nil(t, err)
+	require.False(t, providers)
+	require.True(t, board)
+
+	s.ModelReaders = "providers, board"
+	providers, board, err = s.ModelReaderGroups()
+	require.Nil(t, err)
+	require.True(t, providers)
+	require.True(t, board)
+
+	s.ModelReaders = "everyone"
+	_, _, err = s.ModelReaderGroups()
+	require.NotNil(t, err)
+}
+
+func TestModelDarc(t *testing.T) {
+	consumer := darc.NewSignerEd25519(nil, nil)
+	board := darc.NewSignerEd25519(nil, nil)
+	d, err := vanilla.ModelDarc(consumer.Identity(),
+		[]darc.Identity{board.Identity()}, "Model")
+	require.Nil(t, err)
+	require.NotNil(t, d)
+
+	_, err = vanilla.ModelDarc(consumer.Identity(), nil, "Model")
+	require.NotNil(t, err)
+}
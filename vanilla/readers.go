@@ -0,0 +1,567 @@
+package vanilla
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format is the file format of a dataset
+type Format string
+
+const (
+	// CSV files have a header line naming the columns
+	CSV Format = "csv"
+	// JSONLines files have a flat json object per line, whose keys name the
+	// columns
+	JSONLines Format = "jsonl"
+	// LibSVM files have a label followed by sparse index:value features per
+	// line. The label column is named "label" and the features are named by
+	// their index.
+	LibSVM Format = "libsvm"
+	// ARFF files are the Weka attribute-relation files, whose nominal
+	// attributes are loaded as categorical columns
+	ARFF Format = "arff"
+)
+
+// ErrUnknownFormat is reported for formats that have no reader
+var ErrUnknownFormat = errors.New("unknown dataset format")
+
+// FormatOf returns the format of a dataset file given its extension, files
+// with an unknown extension being csv files
+func FormatOf(fileName string) Format {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".jsonl", ".ndjson":
+		return JSONLines
+	case ".libsvm", ".svm":
+		return LibSVM
+	case ".arff":
+		return ARFF
+	}
+	return CSV
+}
+
+// OpenDataSource opens a dataset in the format set in opts, or given by the
+// extension of the file when opts doesn't set it
+func OpenDataSource(fileName string, opts *LoadOptions) (DataSource, error) {
+	open, err := recordOpener(fileName, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newRecordSource(fileName, opts, open)
+}
+
+// recordOpener returns the function opening the records of a dataset
+func recordOpener(fileName string, opts *LoadOptions) (
+	func(string) (recordReader, error), error) {
+	format := FormatOf(fileName)
+	if opts != nil && opts.Format != "" {
+		format = opts.Format
+	}
+	switch format {
+	case CSV:
+		return openCSV, nil
+	case JSONLines:
+		return openJSONLines, nil
+	case LibSVM:
+		return openLibSVM, nil
+	case ARFF:
+		return openARFF, nil
+	}
+	return nil, &LoadError{File: fileName,
+		Err: fmt.Errorf("%v: %q", ErrUnknownFormat, format)}
+}
+
+// lineReader reads a text file line by line, skipping blank lines and the
+// lines starting with a comment prefix
+type lineReader struct {
+	file    *os.File
+	scanner *bufio.Scanner
+	comment string
+	line    int
+}
+
+func newLineReader(fileName string, comment string) (*lineReader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, &LoadError{File: fileName, Err: err}
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &lineReader{file: file, scanner: scanner, comment: comment}, nil
+}
+
+// next returns the next line that isn't blank nor a comment, and its number
+func (r *lineReader) next() (string, int, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" || (r.comment != "" && strings.HasPrefix(line,
+			r.comment)) {
+			continue
+		}
+		return line, r.line, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return "", r.line, &readFailure{err}
+	}
+	return "", r.line, io.EOF
+}
+
+func (r *lineReader) Close() error {
+	return r.file.Close()
+}
+
+// JSONLinesSource is a DataSource reading a JSON Lines file
+type JSONLinesSource struct {
+	*recordSource
+}
+
+// NewJSONLinesSource opens a JSON Lines dataset, whose columns are selected
+// by opts, which may be nil
+func NewJSONLinesSource(fileName string, opts *LoadOptions) (
+	*JSONLinesSource, error) {
+	source, err := newRecordSource(fileName, opts, openJSONLines)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesSource{source}, nil
+}
+
+// jsonRecords reads the records of a JSON Lines file. The columns are the
+// keys of the first object, in order, and null or absent values are missing
+// cells.
+type jsonRecords struct {
+	*lineReader
+	headers []string
+	index   map[string]int
+	// first is the first line, which is read to get the headers
+	first    string
+	firstRow int
+}
+
+func openJSONLines(fileName string) (recordReader, error) {
+	lines, err := newLineReader(fileName, "")
+	if err != nil {
+		return nil, err
+	}
+	first, row, err := lines.next()
+	if err == nil {
+		var headers []string
+		headers, err = objectKeys(first)
+		if err == nil {
+			r := &jsonRecords{lineReader: lines, headers: headers,
+				index: make(map[string]int), first: first, firstRow: row}
+			for i, h := range headers {
+				r.index[h] = i
+			}
+			return r, nil
+		}
+	}
+	lines.Close()
+	if err == io.EOF {
+		return nil, &LoadError{File: fileName, Err: ErrNoHeader}
+	}
+	return nil, &LoadError{File: fileName, Row: row, Err: err}
+}
+
+// objectKeys returns the keys of a json object in the order they appear
+func objectKeys(line string) ([]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(line))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, errors.New("not a json object")
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, token.(string))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (r *jsonRecords) Headers() []string {
+	return r.headers
+}
+
+func (r *jsonRecords) Read() ([]string, int, error) {
+	line, row := r.first, r.firstRow
+	if line != "" {
+		r.first = ""
+	} else {
+		var err error
+		line, row, err = r.next()
+		if err != nil {
+			return nil, row, err
+		}
+	}
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+	object := make(map[string]interface{})
+	if err := decoder.Decode(&object); err != nil {
+		return nil, row, err
+	}
+	record := make([]string, len(r.headers))
+	for key, value := range object {
+		i, ok := r.index[key]
+		if !ok {
+			return nil, row, fmt.Errorf("%v: %q", ErrUnknownColumn, key)
+		}
+		switch v := value.(type) {
+		case nil:
+		case json.Number:
+			record[i] = v.String()
+		case string:
+			record[i] = v
+		case bool:
+			record[i] = strconv.FormatBool(v)
+		default:
+			return nil, row, fmt.Errorf("field %q isn't a scalar", key)
+		}
+	}
+	return record, row, nil
+}
+
+// LibSVMSource is a DataSource reading a LibSVM file
+type LibSVMSource struct {
+	*recordSource
+}
+
+// NewLibSVMSource opens a LibSVM dataset, whose columns are selected by opts,
+// which may be nil
+func NewLibSVMSource(fileName string, opts *LoadOptions) (*LibSVMSource,
+	error) {
+	source, err := newRecordSource(fileName, opts, openLibSVM)
+	if err != nil {
+		return nil, err
+	}
+	return &LibSVMSource{source}, nil
+}
+
+// libSVMRecords reads the records of a LibSVM file. The file is scanned once
+// when opened to find the highest feature index, absent features are 0.
+type libSVMRecords struct {
+	*lineReader
+	headers []string
+}
+
+func openLibSVM(fileName string) (recordReader, error) {
+	lines, err := newLineReader(fileName, "#")
+	if err != nil {
+		return nil, err
+	}
+	dimension := 0
+	for {
+		line, row, err := lines.next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			var index int
+			index, err = maxLibSVMIndex(line)
+			if index > dimension {
+				dimension = index
+			}
+		}
+		if err != nil {
+			lines.Close()
+			return nil, &LoadError{File: fileName, Row: row, Err: err}
+		}
+	}
+	lines.Close()
+	if dimension == 0 {
+		return nil, &LoadError{File: fileName, Err: ErrNoFeatures}
+	}
+
+	headers := make([]string, dimension+1)
+	for i := 1; i <= dimension; i++ {
+		headers[i-1] = strconv.Itoa(i)
+	}
+	headers[dimension] = "label"
+	lines, err = newLineReader(fileName, "#")
+	if err != nil {
+		return nil, err
+	}
+	return &libSVMRecords{lineReader: lines, headers: headers}, nil
+}
+
+// libSVMFields splits a LibSVM line in its label and its features, dropping
+// trailing comments
+func libSVMFields(line string) ([]string, error) {
+	if i := strings.Index(line, "#"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, errors.New("missing label")
+	}
+	return fields, nil
+}
+
+// maxLibSVMIndex returns the highest feature index of a LibSVM line
+func maxLibSVMIndex(line string) (int, error) {
+	fields, err := libSVMFields(line)
+	if err != nil {
+		return 0, err
+	}
+	max := 0
+	for _, field := range fields[1:] {
+		index, _, err := parseLibSVMFeature(field)
+		if err == errLibSVMQid {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		if index > max {
+			max = index
+		}
+	}
+	return max, nil
+}
+
+// errLibSVMQid is returned for the query ids of ranking datasets, which are
+// skipped
+var errLibSVMQid = errors.New("query id")
+
+// parseLibSVMFeature parses an index:value feature
+func parseLibSVMFeature(field string) (int, string, error) {
+	parts := strings.SplitN(field, ":", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("malformed feature %q", field)
+	}
+	if parts[0] == "qid" {
+		return 0, "", errLibSVMQid
+	}
+	index, err := strconv.Atoi(parts[0])
+	if err != nil || index < 1 {
+		return 0, "", fmt.Errorf("malformed feature index %q", parts[0])
+	}
+	return index, parts[1], nil
+}
+
+func (r *libSVMRecords) Headers() []string {
+	return r.headers
+}
+
+func (r *libSVMRecords) Read() ([]string, int, error) {
+	line, row, err := r.next()
+	if err != nil {
+		return nil, row, err
+	}
+	fields, err := libSVMFields(line)
+	if err != nil {
+		return nil, row, err
+	}
+	dimension := len(r.headers) - 1
+	record := make([]string, len(r.headers))
+	for i := 0; i < dimension; i++ {
+		record[i] = "0"
+	}
+	record[dimension] = fields[0]
+	for _, field := range fields[1:] {
+		index, value, err := parseLibSVMFeature(field)
+		if err == errLibSVMQid {
+			continue
+		}
+		if err != nil {
+			return nil, row, err
+		}
+		// The dimension was found when opening the file
+		if index > dimension {
+			return nil, row, ErrFieldsCount
+		}
+		record[index-1] = value
+	}
+	return record, row, nil
+}
+
+// ARFFSource is a DataSource reading an ARFF file
+type ARFFSource struct {
+	*recordSource
+}
+
+// NewARFFSource opens an ARFF dataset, whose columns are selected by opts,
+// which may be nil
+func NewARFFSource(fileName string, opts *LoadOptions) (*ARFFSource, error) {
+	source, err := newRecordSource(fileName, opts, openARFF)
+	if err != nil {
+		return nil, err
+	}
+	return &ARFFSource{source}, nil
+}
+
+// arffRecords reads the records of an ARFF file. Nominal attributes are
+// categorical columns, dummy encoded unless they are the label, and ?
+// marks missing cells.
+type arffRecords struct {
+	*lineReader
+	headers []string
+	schemas []ColumnSchema
+}
+
+func openARFF(fileName string) (recordReader, error) {
+	lines, err := newLineReader(fileName, "%")
+	if err != nil {
+		return nil, err
+	}
+	r := &arffRecords{lineReader: lines}
+	for {
+		line, row, err := lines.next()
+		if err == io.EOF {
+			err = ErrNoHeader
+		}
+		if err == nil {
+			var done bool
+			done, err = r.parseHeader(line)
+			if done {
+				return r, nil
+			}
+		}
+		if err != nil {
+			lines.Close()
+			return nil, &LoadError{File: fileName, Row: row, Err: err}
+		}
+	}
+}
+
+// parseHeader parses a line of the header, it returns true when the data
+// section starts
+func (r *arffRecords) parseHeader(line string) (bool, error) {
+	keyword := strings.ToLower(strings.Fields(line)[0])
+	switch keyword {
+	case "@relation":
+		return false, nil
+	case "@data":
+		if len(r.headers) == 0 {
+			return false, ErrNoHeader
+		}
+		return true, nil
+	case "@attribute":
+	default:
+		return false, fmt.Errorf("unexpected %q in the header", keyword)
+	}
+
+	rest := strings.TrimSpace(line[len(keyword):])
+	name, rest, err := arffToken(rest)
+	if err != nil {
+		return false, err
+	}
+	if rest == "" {
+		return false, fmt.Errorf("attribute %q has no type", name)
+	}
+	schema := ColumnSchema{Name: name, Type: Numeric}
+	if strings.HasPrefix(rest, "{") {
+		end := strings.LastIndex(rest, "}")
+		if end < 0 {
+			return false, fmt.Errorf("attribute %q has unclosed values", name)
+		}
+		values, err := splitARFF(rest[1:end])
+		if err != nil {
+			return false, err
+		}
+		schema.Type = Categorical
+		schema.Categories = values
+	} else {
+		switch strings.ToLower(strings.Fields(rest)[0]) {
+		case "numeric", "real", "integer":
+		default:
+			return false, fmt.Errorf("attribute %q has unsupported type %q",
+				name, rest)
+		}
+	}
+	r.headers = append(r.headers, name)
+	r.schemas = append(r.schemas, schema)
+	return false, nil
+}
+
+// arffToken returns the first, possibly quoted, token of s and the rest of s
+func arffToken(s string) (string, string, error) {
+	if s == "" {
+		return "", "", errors.New("missing attribute name")
+	}
+	if quote := s[0]; quote == '\'' || quote == '"' {
+		end := strings.IndexByte(s[1:], quote)
+		if end < 0 {
+			return "", "", fmt.Errorf("unclosed quote in %q", s)
+		}
+		return s[1 : end+1], strings.TrimSpace(s[end+2:]), nil
+	}
+	end := strings.IndexAny(s, " \t{")
+	if end < 0 {
+		return "", "", fmt.Errorf("attribute %q has no type", s)
+	}
+	return s[:end], strings.TrimSpace(s[end:]), nil
+}
+
+// splitARFF splits comma-separated values, which can be quoted
+func splitARFF(s string) ([]string, error) {
+	var values []string
+	var value bytes.Buffer
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			value.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			values = append(values, strings.TrimSpace(value.String()))
+			value.Reset()
+		default:
+			value.WriteByte(c)
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in %q", s)
+	}
+	return append(values, strings.TrimSpace(value.String())), nil
+}
+
+func (r *arffRecords) Headers() []string {
+	return r.headers
+}
+
+func (r *arffRecords) Schemas() []ColumnSchema {
+	return r.schemas
+}
+
+func (r *arffRecords) Read() ([]string, int, error) {
+	line, row, err := r.next()
+	if err != nil {
+		return nil, row, err
+	}
+	if strings.HasPrefix(line, "{") {
+		return nil, row, errors.New("sparse ARFF data isn't supported")
+	}
+	record, err := splitARFF(line)
+	if err != nil {
+		return nil, row, err
+	}
+	for i, v := range record {
+		if v == "?" {
+			record[i] = ""
+		}
+	}
+	return record, row, nil
+}
@@ -0,0 +1,101 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatOf(t *testing.T) {
+	require.Equal(t, vanilla.CSV, vanilla.FormatOf("tests/test2.csv"))
+	require.Equal(t, vanilla.JSONLines, vanilla.FormatOf("tests/test2.jsonl"))
+	require.Equal(t, vanilla.LibSVM, vanilla.FormatOf("tests/test2.libsvm"))
+	require.Equal(t, vanilla.ARFF, vanilla.FormatOf("tests/DATA.ARFF"))
+	require.Equal(t, vanilla.CSV, vanilla.FormatOf("tests/test2.txt"))
+
+	_, err := vanilla.OpenDataSource("tests/test2.csv",
+		&vanilla.LoadOptions{Format: "parquet"})
+	require.NotNil(t, err)
+}
+
+func TestLoadJSONLines(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.jsonl",
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, "label", dataset.Label)
+	require.Equal(t, []string{"field1", "field2", "field3"}, dataset.Features)
+	require.Equal(t, 3, len(dataset.Points))
+	// Keys can come in any order
+	require.Equal(t, []float64{1, 2, 3}, dataset.Points[2].Variables)
+	// The null cell and the unknown key are rejected
+	require.Equal(t, 2, len(dataset.Rejected))
+	require.Equal(t, 5, dataset.Rejected[0].Row)
+	require.Equal(t, vanilla.ErrMissingValue, dataset.Rejected[0].Err)
+	require.Equal(t, 6, dataset.Rejected[1].Row)
+
+	dataset, err = vanilla.LoadDataset("tests/test2.jsonl",
+		&vanilla.LoadOptions{Lenient: true, Missing: &vanilla.MissingPolicy{
+			Strategy: vanilla.FillConstant, Value: -1}})
+	require.Nil(t, err)
+	require.Equal(t, 4, len(dataset.Points))
+	require.Equal(t, []float64{5, -1, 7}, dataset.Points[3].Variables)
+}
+
+func TestLoadLibSVM(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.libsvm", nil)
+	require.Nil(t, err)
+	require.Equal(t, "label", dataset.Label)
+	require.Equal(t, []string{"1", "2", "3"}, dataset.Features)
+	require.Equal(t, 5, len(dataset.Points))
+	require.Equal(t, []float64{12.5, 3, 4}, dataset.Points[0].Variables)
+	require.Equal(t, []float64{0, 0, 0}, dataset.Points[1].Variables)
+	require.Equal(t, 2.0, dataset.Points[1].Observed)
+	require.Equal(t, []float64{1, 2, 3}, dataset.Points[2].Variables)
+	// Absent features are 0
+	require.Equal(t, []float64{5, 0, 7}, dataset.Points[3].Variables)
+}
+
+func TestLoadARFF(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/categorical.arff",
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, "Classification", dataset.Label)
+	require.Equal(t, []string{"Age", "Sex=M", "Medical site=Lisbon",
+		"Medical site=Porto"}, dataset.Features)
+	require.Equal(t, 2, len(dataset.Points))
+	require.Equal(t, []float64{48, 0, 0, 0}, dataset.Points[0].Variables)
+	require.Equal(t, []float64{83, 1, 1, 0}, dataset.Points[1].Variables)
+	require.Equal(t, 1.0, dataset.Points[0].Observed)
+	require.Equal(t, 0.0, dataset.Points[1].Observed)
+	// The missing age and the unknown site are rejected
+	require.Equal(t, 2, len(dataset.Rejected))
+	require.Equal(t, vanilla.ErrMissingValue, dataset.Rejected[0].Err)
+	require.Equal(t, "Medical site", dataset.Rejected[1].Column)
+	require.Equal(t, vanilla.ErrUnknownCategory, dataset.Rejected[1].Err)
+
+	stats, err := vanilla.ComputeImputationStats("tests/categorical.arff",
+		nil, vanilla.FillMean)
+	require.Nil(t, err)
+	require.Equal(t, vanilla.ImputationStats{"Age": 199.0 / 3}, stats)
+}
+
+func TestLoadARFFMalformedHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "arff")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "malformed.arff")
+	for _, attribute := range []string{"@attribute 'name'", "@attribute name",
+		"@attribute 'name", "@attribute name {a,b", "@attribute name string"} {
+		require.Nil(t, ioutil.WriteFile(fileName, []byte("@relation test\n"+
+			attribute+"\n@attribute label numeric\n@data\na,1\n"), 0644))
+		_, err := vanilla.LoadDataset(fileName, nil)
+		require.NotNil(t, err, attribute)
+		loadErr, ok := err.(*vanilla.LoadError)
+		require.True(t, ok, attribute)
+		require.Equal(t, 2, loadErr.Row, attribute)
+	}
+}
@@ -0,0 +1,215 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+
+	"github.com/sajari/regression"
+)
+
+// ErrScalerColumns is reported when a scaler doesn't scale the features of a
+// dataset
+var ErrScalerColumns = errors.New("scaler features don't match the dataset")
+
+// ScalingMethod tells how a Scaler is fitted
+type ScalingMethod string
+
+const (
+	// MinMax scales the features to [0, 1]
+	MinMax ScalingMethod = "minmax"
+	// ZScore scales the features to a zero mean and a unit standard deviation
+	ZScore ScalingMethod = "zscore"
+)
+
+// Scaler standardizes the features of data points, a feature x becoming
+// (x - Offset) / Scale. It is fitted by the consumer and published to the data
+// providers, who scale their points before they are encrypted or secret
+// shared, so that every feature fits in the same range.
+type Scaler struct {
+	Method ScalingMethod
+	// Features are the names of the scaled features, in order
+	Features []string
+	Offsets  []float64
+	Scales   []float64
+}
+
+// FitScaler fits a scaler on the features of points named by features.
+// Constant features are only shifted.
+func FitScaler(method ScalingMethod, features []string,
+	points []MlDataPoint) (*Scaler, error) {
+	if len(points) == 0 {
+		return nil, errors.New("can't fit a scaler on an empty dataset")
+	}
+	n := len(features)
+	s := &Scaler{Method: method, Features: features,
+		Offsets: make([]float64, n), Scales: make([]float64, n)}
+	columns := make([][]float64, n)
+	for _, point := range points {
+		if len(point.Variables) != n {
+			return nil, ErrFieldsCount
+		}
+		for j, v := range point.Variables {
+			columns[j] = append(columns[j], v)
+		}
+	}
+	for j, values := range columns {
+		switch method {
+		case MinMax:
+			min, max := values[0], values[0]
+			for _, v := range values {
+				min = math.Min(min, v)
+				max = math.Max(max, v)
+			}
+			s.Offsets[j], s.Scales[j] = min, max-min
+		case ZScore:
+			m := mean(values)
+			variance := 0.0
+			for _, v := range values {
+				variance += (v - m) * (v - m)
+			}
+			s.Offsets[j] = m
+			s.Scales[j] = math.Sqrt(variance / float64(len(values)))
+		default:
+			return nil, fmt.Errorf("unknown scaling method %q", method)
+		}
+		if s.Scales[j] == 0 {
+			s.Scales[j] = 1
+		}
+	}
+	return s, nil
+}
+
+// LoadScaler reads a scaler saved by Scaler.Save
+func LoadScaler(fileName string) (*Scaler, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scaler{}
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, errors.New("couldn't decode scaler: " + err.Error())
+	}
+	if len(s.Offsets) != len(s.Features) || len(s.Scales) != len(s.Features) {
+		return nil, errors.New("scaler has mismatched parameters")
+	}
+	return s, nil
+}
+
+// Save writes the scaler as json to a file
+func (s *Scaler) Save(fileName string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode scaler: " + err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// check returns an error if the scaler doesn't scale the given features
+func (s *Scaler) check(features []string) error {
+	if len(features) != len(s.Features) {
+		return ErrScalerColumns
+	}
+	for j, name := range features {
+		if s.Features[j] != name {
+			return ErrScalerColumns
+		}
+	}
+	return nil
+}
+
+// Scale scales the variables of a point in place
+func (s *Scaler) Scale(point *MlDataPoint) error {
+	if len(point.Variables) != len(s.Features) {
+		return ErrFieldsCount
+	}
+	for j := range point.Variables {
+		point.Variables[j] = (point.Variables[j] - s.Offsets[j]) / s.Scales[j]
+	}
+	return nil
+}
+
+// ScalePoints scales the variables of points in place
+func (s *Scaler) ScalePoints(points []MlDataPoint) error {
+	for i := range points {
+		if err := s.Scale(&points[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnscaleCoefficients converts the coefficients of a linear model trained on
+// scaled features, the intercept first, to the coefficients of the same model
+// on the original features
+func (s *Scaler) UnscaleCoefficients(coeffs []float64) ([]float64, error) {
+	if len(coeffs) != len(s.Features)+1 {
+		return nil, errors.New("coefficients don't match the scaler features")
+	}
+	unscaled := make([]float64, len(coeffs))
+	unscaled[0] = coeffs[0]
+	for j := range s.Features {
+		unscaled[j+1] = coeffs[j+1] / s.Scales[j]
+		unscaled[0] -= coeffs[j+1] * s.Offsets[j] / s.Scales[j]
+	}
+	return unscaled, nil
+}
+
+// unscaling returns the matrix of UnscaleCoefficients, which is linear
+func (s *Scaler) unscaling() [][]float64 {
+	n := len(s.Features) + 1
+	t := newMatrix(n, n)
+	t[0][0] = 1
+	for j := range s.Features {
+		t[0][j+1] = -s.Offsets[j] / s.Scales[j]
+		t[j+1][j+1] = 1 / s.Scales[j]
+	}
+	return t
+}
+
+// UnscaledFormula returns the formula of a regression trained on scaled
+// features, in the units of the original features
+func (s *Scaler) UnscaledFormula(r *regression.Regression) (string, error) {
+	coeffs := make([]float64, len(s.Features)+1)
+	for i := range coeffs {
+		coeffs[i] = r.Coeff(i)
+	}
+	unscaled, err := s.UnscaleCoefficients(coeffs)
+	if err != nil {
+		return "", err
+	}
+	return linearFormula("Predicted", s.Features, unscaled), nil
+}
+
+// UnscaleModel returns a copy of a linear or logistic model trained on scaled
+// features, which predicts from and describes the original features
+func (s *Scaler) UnscaleModel(m Model) (Model, error) {
+	switch m := m.(type) {
+	case *LinearModel:
+		coeffs, err := s.UnscaleCoefficients(m.Coefficients)
+		if err != nil {
+			return nil, err
+		}
+		unscaled := &LinearModel{Columns: m.Columns, Coefficients: coeffs}
+		if m.Inference != nil {
+			unscaled.Inference, err = m.Inference.transform(s.unscaling(),
+				coeffs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return unscaled, nil
+	case *LogisticModel:
+		coeffs, err := s.UnscaleCoefficients(m.Coefficients)
+		if err != nil {
+			return nil, err
+		}
+		unscaled := *m
+		unscaled.Coefficients = coeffs
+		return &unscaled, nil
+	}
+	return nil, fmt.Errorf("can't unscale a %T", m)
+}
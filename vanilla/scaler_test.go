@@ -0,0 +1,89 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFitScaler(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+
+	scaler, err := vanilla.FitScaler(vanilla.MinMax, dataset.Features, points)
+	require.Nil(t, err)
+	require.Equal(t, []float64{0, 0, 0}, scaler.Offsets)
+	require.Equal(t, []float64{12.5, 10, 11}, scaler.Scales)
+	require.Nil(t, scaler.ScalePoints(points))
+	require.Equal(t, []float64{1, 0.3, 4.0 / 11}, points[0].Variables)
+	require.Equal(t, 1.0, points[0].Label)
+
+	// The points share their variables with the dataset, which was scaled
+	dataset, err = vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	points = dataset.MlDataPoints("test")
+	scaler, err = vanilla.FitScaler(vanilla.ZScore, dataset.Features, points)
+	require.Nil(t, err)
+	require.InDelta(t, 4.2, scaler.Offsets[1], 1e-9)
+	require.Nil(t, scaler.ScalePoints(points))
+	sum := 0.0
+	for _, p := range points {
+		sum += p.Variables[1]
+	}
+	require.InDelta(t, 0, sum, 1e-9)
+
+	_, err = vanilla.FitScaler("log", dataset.Features, points)
+	require.NotNil(t, err)
+}
+
+func TestScalerSave(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	scaler, err := vanilla.FitScaler(vanilla.ZScore, dataset.Features,
+		dataset.MlDataPoints("test"))
+	require.Nil(t, err)
+	dir, err := ioutil.TempDir("", "scaler")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "scaler.json")
+	require.Nil(t, scaler.Save(fileName))
+	loaded, err := vanilla.LoadScaler(fileName)
+	require.Nil(t, err)
+	require.Equal(t, scaler, loaded)
+}
+
+func TestLoadScaledDataset(t *testing.T) {
+	raw, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	scaler, err := vanilla.FitScaler(vanilla.ZScore, raw.Features,
+		raw.MlDataPoints("test"))
+	require.Nil(t, err)
+	scaled, err := vanilla.LoadDataset("tests/test2.csv",
+		&vanilla.LoadOptions{Scaler: scaler})
+	require.Nil(t, err)
+
+	r, err := vanilla.TrainNamedRegressionModel(raw.Points, &raw.Columns)
+	require.Nil(t, err)
+	s, err := vanilla.TrainNamedRegressionModel(scaled.Points, &scaled.Columns)
+	require.Nil(t, err)
+	coeffs := []float64{s.Coeff(0), s.Coeff(1), s.Coeff(2), s.Coeff(3)}
+	unscaled, err := scaler.UnscaleCoefficients(coeffs)
+	require.Nil(t, err)
+	for i, c := range unscaled {
+		require.InDelta(t, r.Coeff(i), c, 1e-6)
+	}
+	formula, err := scaler.UnscaledFormula(s)
+	require.Nil(t, err)
+	require.Equal(t, r.Formula, formula)
+
+	// The scaler must match the selected features
+	_, err = vanilla.LoadDataset("tests/test2.csv",
+		&vanilla.LoadOptions{Scaler: scaler, Exclude: []string{"field2"}})
+	require.NotNil(t, err)
+	require.Equal(t, vanilla.ErrScalerColumns, err.(*vanilla.LoadError).Err)
+}
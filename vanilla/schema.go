@@ -0,0 +1,216 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// ColumnType tells how the cells of a column are turned into variables
+type ColumnType string
+
+const (
+	// Numeric cells are parsed as floats
+	Numeric ColumnType = "numeric"
+	// Categorical cells take one of the column's categories
+	Categorical ColumnType = "categorical"
+	// Boolean cells are encoded as 1 (true, yes, 1) or 0 (false, no, 0)
+	Boolean ColumnType = "boolean"
+)
+
+// Encoding tells how a categorical column is turned into variables
+type Encoding string
+
+const (
+	// OneHot encodes a category as one variable per category, set to 1 for
+	// the cell's category and to 0 for the others. The variables always sum
+	// to 1, as the intercept, so least squares can't fit them.
+	OneHot Encoding = "onehot"
+	// Dummy encodes a category as OneHot does without the variable of the
+	// first category, the reference, whose cells have all variables 0. It is
+	// the default.
+	Dummy Encoding = "dummy"
+	// Ordinal encodes a category as its index in the column's categories
+	Ordinal Encoding = "ordinal"
+)
+
+// ErrUnknownCategory is reported for cells that aren't one of the categories
+// declared in the schema
+var ErrUnknownCategory = errors.New("unknown category")
+
+// ErrNotBoolean is reported for cells of boolean columns that can't be read
+// as a boolean
+var ErrNotBoolean = errors.New("not a boolean")
+
+// ColumnSchema declares how a column of a dataset is encoded
+type ColumnSchema struct {
+	// Name is the header of the column
+	Name string
+	Type ColumnType
+	// Encoding is only used by categorical columns
+	Encoding Encoding `json:",omitempty"`
+	// Categories are the values a categorical column can take, in order
+	Categories []string `json:",omitempty"`
+}
+
+// Schema declares the encoding of every column of a dataset, so that data
+// providers and consumers agree on it before any data is written to Calypso
+type Schema struct {
+	// Label is the name of the label column, which is ordinal encoded if it
+	// is categorical
+	Label string
+	// Columns are the columns of the dataset that are loaded, features are
+	// in this order
+	Columns []ColumnSchema
+}
+
+// LoadSchema reads a schema saved by Schema.Save
+func LoadSchema(fileName string) (*Schema, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	schema := &Schema{}
+	err = json.Unmarshal(data, schema)
+	if err != nil {
+		return nil, errors.New("couldn't decode schema: " + err.Error())
+	}
+	return schema, schema.Validate()
+}
+
+// Save writes the schema as json to a file
+func (s *Schema) Save(fileName string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return errors.New("couldn't encode schema: " + err.Error())
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// Validate checks that the schema is consistent
+func (s *Schema) Validate() error {
+	names := make(map[string]bool, len(s.Columns))
+	for _, c := range s.Columns {
+		if names[c.Name] {
+			return fmt.Errorf("column %q is declared twice", c.Name)
+		}
+		names[c.Name] = true
+		switch c.Type {
+		case Numeric, Boolean:
+		case Categorical:
+			if c.Encoding != "" && c.Encoding != OneHot &&
+				c.Encoding != Dummy && c.Encoding != Ordinal {
+				return fmt.Errorf("column %q has unknown encoding %q",
+					c.Name, c.Encoding)
+			}
+			if len(c.Categories) == 0 {
+				return fmt.Errorf("column %q has no categories", c.Name)
+			}
+			if c.Name == s.Label && c.encoding() != Ordinal {
+				return fmt.Errorf("label column %q must be ordinal encoded",
+					c.Name)
+			}
+		default:
+			return fmt.Errorf("column %q has unknown type %q", c.Name, c.Type)
+		}
+	}
+	if !names[s.Label] {
+		return fmt.Errorf("label column %q isn't declared", s.Label)
+	}
+	if len(s.Columns) < 2 {
+		return ErrNoFeatures
+	}
+	return nil
+}
+
+// Column returns the schema of the column with the given name, nil if it
+// isn't declared
+func (s *Schema) Column(name string) *ColumnSchema {
+	for i := range s.Columns {
+		if s.Columns[i].Name == name {
+			return &s.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Features returns the names of the variables the feature columns are
+// encoded into. One-hot and dummy encoded columns give a variable per
+// category, but the reference one for dummy encoding, named column=category.
+func (s *Schema) Features() []string {
+	var features []string
+	for _, c := range s.Columns {
+		if c.Name != s.Label {
+			features = append(features, c.Variables()...)
+		}
+	}
+	return features
+}
+
+// Variables returns the names of the variables the column is encoded into
+func (c *ColumnSchema) Variables() []string {
+	if c.Type != Categorical || c.encoding() == Ordinal {
+		return []string{c.Name}
+	}
+	names := make([]string, 0, len(c.Categories))
+	for _, category := range c.categories() {
+		names = append(names, c.Name+"="+category)
+	}
+	return names
+}
+
+// Encode turns a cell of the column into its variables
+func (c *ColumnSchema) Encode(cell string) ([]float64, error) {
+	cell = strings.TrimSpace(cell)
+	switch c.Type {
+	case Categorical:
+		for i, category := range c.Categories {
+			if category != cell {
+				continue
+			}
+			if c.encoding() == Ordinal {
+				return []float64{float64(i)}, nil
+			}
+			encoded := make([]float64, len(c.categories()))
+			// The reference category of dummy encoding has no variable
+			if j := i - len(c.Categories) + len(encoded); j >= 0 {
+				encoded[j] = 1
+			}
+			return encoded, nil
+		}
+		return nil, ErrUnknownCategory
+	case Boolean:
+		switch strings.ToLower(cell) {
+		case "1", "true", "t", "yes", "y":
+			return []float64{1}, nil
+		case "0", "false", "f", "no", "n":
+			return []float64{0}, nil
+		}
+		return nil, ErrNotBoolean
+	default:
+		v, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return nil, err
+		}
+		return []float64{v}, nil
+	}
+}
+
+func (c *ColumnSchema) encoding() Encoding {
+	if c.Encoding == "" {
+		return Dummy
+	}
+	return c.Encoding
+}
+
+// categories returns the categories encoded as variables, all but the
+// reference one for dummy encoding
+func (c *ColumnSchema) categories() []string {
+	if c.encoding() == Dummy && len(c.Categories) > 0 {
+		return c.Categories[1:]
+	}
+	return c.Categories
+}
@@ -0,0 +1,107 @@
+package vanilla_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDatasetWithSchema(t *testing.T) {
+	schema, err := vanilla.LoadSchema("tests/categorical.json")
+	require.Nil(t, err)
+	require.Equal(t, []string{"Age", "Sex=F", "Sex=M", "Site", "Smoker"},
+		schema.Features())
+
+	dataset, err := vanilla.LoadDataset("tests/categorical.csv",
+		&vanilla.LoadOptions{Schema: schema, Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, "Classification", dataset.Label)
+	require.Equal(t, schema.Features(), dataset.Features)
+	require.Equal(t, 3, len(dataset.Points))
+	require.Equal(t, []float64{48, 1, 0, 0, 1}, dataset.Points[0].Variables)
+	require.Equal(t, 1.0, dataset.Points[0].Observed)
+	require.Equal(t, []float64{82, 1, 0, 2, 0}, dataset.Points[2].Variables)
+	require.Equal(t, 0.0, dataset.Points[2].Observed)
+	// Madrid isn't one of the sites of the schema
+	require.Equal(t, 1, len(dataset.Rejected))
+	require.Equal(t, "Site", dataset.Rejected[0].Column)
+	require.Equal(t, vanilla.ErrUnknownCategory, dataset.Rejected[0].Err)
+
+	points := dataset.MlDataPoints("test")
+	require.Equal(t, dataset.Points[1].Variables, points[1].Variables)
+	require.Equal(t, dataset.Points[1].Observed, points[1].Label)
+}
+
+func TestSchemaSave(t *testing.T) {
+	schema, err := vanilla.LoadSchema("tests/categorical.json")
+	require.Nil(t, err)
+	dir, err := ioutil.TempDir("", "schema")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "schema.json")
+	require.Nil(t, schema.Save(fileName))
+	loaded, err := vanilla.LoadSchema(fileName)
+	require.Nil(t, err)
+	require.Equal(t, schema, loaded)
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &vanilla.Schema{Label: "y", Columns: []vanilla.ColumnSchema{
+		{Name: "x", Type: vanilla.Numeric},
+		{Name: "y", Type: vanilla.Categorical, Categories: []string{"a", "b"}},
+	}}
+	// The label must be ordinal encoded, dummy encoding being the default
+	require.NotNil(t, schema.Validate())
+	schema.Columns[1].Encoding = vanilla.Ordinal
+	require.Nil(t, schema.Validate())
+	schema.Columns[0].Type = "text"
+	require.NotNil(t, schema.Validate())
+}
+
+func TestDummyEncoding(t *testing.T) {
+	// The label depends on a site with effects 0, 3 and -2
+	dir, err := ioutil.TempDir("", "schema")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "sites.csv")
+	sites := []string{"Coimbra", "Lisbon", "Porto"}
+	effects := []float64{0, 3, -2}
+	rows := "x,site,y\n"
+	for i := 0; i < 30; i++ {
+		x := float64(i % 7)
+		rows += fmt.Sprintf("%g,%s,%g\n", x, sites[i%3],
+			1+2*x+effects[i%3])
+	}
+	require.Nil(t, ioutil.WriteFile(fileName, []byte(rows), 0644))
+	schema := &vanilla.Schema{Label: "y", Columns: []vanilla.ColumnSchema{
+		{Name: "x", Type: vanilla.Numeric},
+		{Name: "site", Type: vanilla.Categorical, Categories: sites},
+		{Name: "y", Type: vanilla.Numeric},
+	}}
+	require.Nil(t, schema.Validate())
+	require.Equal(t, []string{"x", "site=Lisbon", "site=Porto"},
+		schema.Features())
+
+	dataset, err := vanilla.LoadDataset(fileName,
+		&vanilla.LoadOptions{Schema: schema})
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+	m, err := train(t, "ols", nil, points)
+	require.Nil(t, err)
+	for j, c := range []float64{1, 2, 3, -2} {
+		require.InDelta(t, c, m.Coefficients[j], 1e-9)
+	}
+
+	// The one-hot variables sum up to the intercept
+	schema.Columns[1].Encoding = vanilla.OneHot
+	dataset, err = vanilla.LoadDataset(fileName,
+		&vanilla.LoadOptions{Schema: schema})
+	require.Nil(t, err)
+	_, err = train(t, "ols", nil, dataset.MlDataPoints("test"))
+	require.NotNil(t, err)
+}
@@ -0,0 +1,241 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/dedis/student_18_ml/vanilla/metrics"
+)
+
+// ParamSpace gives the values tried for every parameter of a trainer
+type ParamSpace map[string][]string
+
+// ParseParamSpace parses comma-separated key=value|value|... parameter
+// values, such as "lambda=0.1|1|10,maxIterations=100|1000"
+func ParseParamSpace(list string) (ParamSpace, error) {
+	space := ParamSpace{}
+	for _, pair := range splitNames(list) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("parameter %q isn't key=values", pair)
+		}
+		for _, v := range strings.Split(kv[1], "|") {
+			if v = strings.TrimSpace(v); v != "" {
+				key := strings.TrimSpace(kv[0])
+				space[key] = append(space[key], v)
+			}
+		}
+	}
+	return space, nil
+}
+
+// grid returns every combination of the values of a space, in a
+// deterministic order
+func (s ParamSpace) grid() []Params {
+	var keys []string
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	grid := []Params{{}}
+	for _, key := range keys {
+		var expanded []Params
+		for _, params := range grid {
+			for _, v := range s[key] {
+				p := Params{key: v}
+				for k, value := range params {
+					p[k] = value
+				}
+				expanded = append(expanded, p)
+			}
+		}
+		grid = expanded
+	}
+	return grid
+}
+
+// SearchOptions configure a hyperparameter search
+type SearchOptions struct {
+	// Folds is the number of cross-validation folds, 5 if 0
+	Folds int
+	// Samples, if set, is the number of parameter combinations drawn at
+	// random from the grid instead of trying all of them
+	Samples int
+	// Split seeds the folds and the drawing of the combinations, and
+	// stratifies the folds of classifications
+	Split SplitOptions
+	// Metric is the metric of the test points scoring a combination: mse,
+	// rmse, mae or r2 for regressions and accuracy, precision, recall, f1 or
+	// auc for binary classifications, only accuracy for classifications of
+	// more labels. It is rmse or accuracy if empty.
+	Metric string
+	// Base are the parameters shared by every combination
+	Base Params
+}
+
+// SearchTrial is the cross-validation of a parameter combination
+type SearchTrial struct {
+	Params Params
+	// Folds are the evaluations on the test points of every fold
+	Folds []*Evaluation `json:",omitempty"`
+	// Score is the mean metric of the folds
+	Score float64
+	// Error tells why the combination couldn't be scored, if it couldn't
+	Error string `json:",omitempty"`
+}
+
+// SearchResult is the outcome of a hyperparameter search
+type SearchResult struct {
+	Trainer string
+	Metric  string
+	// Best are the parameters of the best trial, which is Trials[BestTrial]
+	Best      Params
+	BestScore float64
+	BestTrial int
+	Trials    []*SearchTrial
+}
+
+// Save writes the result as json to a file
+func (r *SearchResult) Save(fileName string) error {
+	return metrics.Save(r, fileName)
+}
+
+// lowerIsBetter tells if smaller values of a metric are better
+func lowerIsBetter(metric string) bool {
+	return metric == "mse" || metric == "rmse" || metric == "mae"
+}
+
+// score returns the value of a metric in an evaluation
+func score(e *Evaluation, metric string) (float64, error) {
+	if r := e.Regression; r != nil {
+		switch metric {
+		case "mse":
+			return r.MSE, nil
+		case "rmse":
+			return r.RMSE, nil
+		case "mae":
+			return r.MAE, nil
+		case "r2":
+			return r.R2, nil
+		}
+	}
+	if c := e.Classification; c != nil {
+		switch {
+		case metric == "accuracy":
+			return c.Accuracy, nil
+		case c.MultiClass:
+			// The other metrics are those of a positive label
+		case metric == "precision":
+			return c.Precision, nil
+		case metric == "recall":
+			return c.Recall, nil
+		case metric == "f1":
+			return c.F1, nil
+		case metric == "auc":
+			if c.AUC != nil {
+				return *c.AUC, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("metric %q isn't available", metric)
+}
+
+// SearchHyperparameters cross-validates the combinations of parameter values
+// of a registered trainer on points and returns the best one. columns and
+// opts may be nil.
+func SearchHyperparameters(trainer string, space ParamSpace,
+	points []MlDataPoint, columns *Columns, opts *SearchOptions) (
+	*SearchResult, error) {
+	var options SearchOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Folds == 0 {
+		options.Folds = 5
+	}
+	splits, err := KFold(MlLabels(points), options.Folds, &options.Split)
+	if err != nil {
+		return nil, err
+	}
+	grid := space.grid()
+	if options.Samples > 0 && options.Samples < len(grid) {
+		r := options.Split.random()
+		r.Shuffle(len(grid), func(i, j int) {
+			grid[i], grid[j] = grid[j], grid[i]
+		})
+		grid = grid[:options.Samples]
+	}
+
+	result := &SearchResult{Trainer: trainer, Metric: options.Metric,
+		BestTrial: -1}
+	for _, params := range grid {
+		for key, v := range options.Base {
+			if _, ok := params[key]; !ok {
+				params[key] = v
+			}
+		}
+		trial := &SearchTrial{Params: params}
+		result.Trials = append(result.Trials, trial)
+		if err := crossValidateTrial(trial, trainer, points, columns, splits,
+			result); err != nil {
+			trial.Error = err.Error()
+			trial.Folds = nil
+			continue
+		}
+		better := trial.Score > result.BestScore
+		if lowerIsBetter(result.Metric) {
+			better = trial.Score < result.BestScore
+		}
+		if result.BestTrial < 0 || better {
+			result.Best, result.BestScore = params, trial.Score
+			result.BestTrial = len(result.Trials) - 1
+		}
+	}
+	if result.BestTrial < 0 {
+		return nil, errors.New("no parameter combination could be scored: " +
+			result.Trials[0].Error)
+	}
+	return result, nil
+}
+
+// crossValidateTrial trains and evaluates a parameter combination on every
+// fold, choosing the metric of the result from the first evaluation if it
+// isn't set
+func crossValidateTrial(trial *SearchTrial, name string, points []MlDataPoint,
+	columns *Columns, splits []*Split, result *SearchResult) error {
+	trainer, err := NewTrainer(name, trial.Params)
+	if err != nil {
+		return err
+	}
+	for _, split := range splits {
+		train, test := split.MlDataPoints(points)
+		model, err := trainer.Train(train, columns)
+		if err != nil {
+			return err
+		}
+		evaluation, err := EvaluateModel(model, test)
+		if err != nil {
+			return err
+		}
+		if result.Metric == "" {
+			result.Metric = "rmse"
+			if evaluation.Classification != nil {
+				result.Metric = "accuracy"
+			}
+		}
+		s, err := score(evaluation, result.Metric)
+		if err != nil {
+			return err
+		}
+		if math.IsNaN(s) {
+			return fmt.Errorf("metric %q isn't defined on a fold",
+				result.Metric)
+		}
+		trial.Folds = append(trial.Folds, evaluation)
+		trial.Score += s / float64(len(splits))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package vanilla_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseParamSpace(t *testing.T) {
+	space, err := vanilla.ParseParamSpace("lambda=0.1|1| 10 ,tolerance=1e-6")
+	require.Nil(t, err)
+	require.Equal(t, vanilla.ParamSpace{"lambda": {"0.1", "1", "10"},
+		"tolerance": {"1e-6"}}, space)
+	_, err = vanilla.ParseParamSpace("lambda")
+	require.NotNil(t, err)
+}
+
+func TestSearchHyperparameters(t *testing.T) {
+	d := linearDataset(t)
+	space := vanilla.ParamSpace{"lambda": {"0", "1", "10000"}}
+	result, err := vanilla.SearchHyperparameters("ridge", space, d.Points,
+		nil, &vanilla.SearchOptions{Folds: 3})
+	require.Nil(t, err)
+	require.Equal(t, "rmse", result.Metric)
+	require.Equal(t, 3, len(result.Trials))
+	require.Equal(t, 3, len(result.Trials[0].Folds))
+	// The huge penalty underfits
+	require.NotEqual(t, "10000", result.Best["lambda"])
+	require.True(t, result.Trials[2].Score > result.BestScore)
+	require.Equal(t, result.Best, result.Trials[result.BestTrial].Params)
+
+	// A random search tries some of the combinations, and the failing ones
+	// are reported
+	space = vanilla.ParamSpace{"lambda": {"0", "0.5", "1", "2"},
+		"maxIterations": {"1", "1000"}}
+	result, err = vanilla.SearchHyperparameters("lasso", space, d.Points,
+		nil, &vanilla.SearchOptions{Samples: 5, Metric: "r2",
+			Split: vanilla.SplitOptions{Seed: 2}})
+	require.Nil(t, err)
+	require.Equal(t, 5, len(result.Trials))
+	require.Equal(t, "1000", result.Best["maxIterations"])
+	for _, trial := range result.Trials {
+		if trial.Params["maxIterations"] == "1" {
+			require.Contains(t, trial.Error, "converge")
+		}
+	}
+	require.True(t, result.BestScore > 0.99)
+
+	dir, err := ioutil.TempDir("", "search")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "search.json")
+	require.Nil(t, result.Save(fileName))
+	data, err := ioutil.ReadFile(fileName)
+	require.Nil(t, err)
+	loaded := &vanilla.SearchResult{}
+	require.Nil(t, json.Unmarshal(data, loaded))
+	require.Equal(t, result.Best, loaded.Best)
+
+	_, err = vanilla.SearchHyperparameters("ridge", space, d.Points, nil,
+		nil)
+	require.NotNil(t, err)
+	_, err = vanilla.SearchHyperparameters("ridge",
+		vanilla.ParamSpace{"lambda": {"1"}}, d.Points, nil,
+		&vanilla.SearchOptions{Metric: "accuracy"})
+	require.NotNil(t, err)
+}
+
+func TestSearchClassifier(t *testing.T) {
+	points := interactions(600, true)
+	result, err := vanilla.SearchHyperparameters("tree",
+		vanilla.ParamSpace{"maxDepth": {"1", "10"}}, points, nil,
+		&vanilla.SearchOptions{Base: vanilla.Params{"classification": "true"},
+			Split: vanilla.SplitOptions{Stratified: true}})
+	require.Nil(t, err)
+	require.Equal(t, "accuracy", result.Metric)
+	require.Equal(t, vanilla.Params{"classification": "true",
+		"maxDepth": "10"}, result.Best)
+
+	// The simulation trains the model with the best parameters
+	s := &vanilla.MlSimulation{Trainer: "tree",
+		TrainerParams: "classification=true", SearchSpace: "maxDepth=1|10",
+		SearchFolds: 3}
+	saved, err := s.TrainModel(points, nil, nil)
+	require.Nil(t, err)
+	require.Equal(t, "10", saved.Params["maxDepth"])
+}
@@ -0,0 +1,336 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+func init() {
+	RegisterTrainer("sgd", newSGDTrainer, decodeSGDModel)
+}
+
+// SGDOptions configure the stochastic gradient descent of a linear or
+// logistic regression
+type SGDOptions struct {
+	// Logistic trains a logistic regression, of the labels mapped by Labels,
+	// instead of a linear regression
+	Logistic bool
+	Labels   *LabelMapping
+	// LearningRate is the step size of the first update, 0.01 if 0, which
+	// decays to LearningRate / (1 + Decay * t) at the update t
+	LearningRate float64
+	Decay        float64
+	// BatchSize points are averaged by every update, 1 if 0
+	BatchSize int
+	// L2 is the weight of the L2 penalty of the coefficients, the intercept
+	// isn't penalized
+	L2 float64
+	// Tolerance converges the training once no coefficient changed by more
+	// over Patience updates in a row, 5 if 0. It never converges if
+	// Tolerance is 0.
+	Tolerance float64
+	Patience  int
+	// Target, if set, is reached once the accuracy of a logistic regression
+	// is at least Target, or the mean squared error of a linear regression at
+	// most Target, on the last Window points, 100 if 0. Every point is
+	// predicted before it is learnt.
+	Target float64
+	Window int
+}
+
+// OnlineSGD trains a linear or logistic regression by stochastic gradient
+// descent, one point at a time, so that the training advances as the points
+// are decrypted and stops once it converged or reached its target
+type OnlineSGD struct {
+	columns Columns
+	options SGDOptions
+	labels  LabelMapping
+	coeffs  []float64
+	// gradient is the sum of the gradients of the points of the batch
+	gradient []float64
+	batch    int
+	seen     int
+	updates  int
+	stable   int
+	// scores are the losses or the correctness of the last points, predicted
+	// before they were learnt
+	scores []float64
+}
+
+// NewOnlineSGD creates an online training of points whose label and features
+// are named by columns. columns and opts may be nil.
+func NewOnlineSGD(columns *Columns, opts *SGDOptions) *OnlineSGD {
+	o := &OnlineSGD{labels: LabelMapping{0, 1}}
+	if columns != nil {
+		o.columns = *columns
+	}
+	if opts != nil {
+		o.options = *opts
+	}
+	if o.options.Labels != nil {
+		o.labels = *o.options.Labels
+	}
+	if o.options.LearningRate == 0 {
+		o.options.LearningRate = 0.01
+	}
+	if o.options.BatchSize == 0 {
+		o.options.BatchSize = 1
+	}
+	if o.options.Patience == 0 {
+		o.options.Patience = 5
+	}
+	if o.options.Window == 0 {
+		o.options.Window = 100
+	}
+	return o
+}
+
+// Add predicts a point with the current model and learns from it. Every
+// point must have the same number of variables.
+func (o *OnlineSGD) Add(point *MlDataPoint) error {
+	if o.coeffs == nil {
+		o.coeffs = make([]float64, len(point.Variables)+1)
+		o.gradient = make([]float64, len(o.coeffs))
+	}
+	if len(point.Variables) != len(o.coeffs)-1 {
+		return ErrFieldsCount
+	}
+	y := point.Label
+	if o.options.Logistic {
+		var err error
+		if y, err = o.labels.binary(point.Label); err != nil {
+			return err
+		}
+	}
+	x := withIntercept(point.Variables)
+	predicted := dot(x, o.coeffs)
+	score := (predicted - y) * (predicted - y)
+	if o.options.Logistic {
+		predicted = sigmoid(predicted)
+		score = 0
+		if (predicted >= 0.5) == (y == 1) {
+			score = 1
+		}
+	}
+	o.scores = append(o.scores, score)
+	if len(o.scores) > o.options.Window {
+		o.scores = o.scores[1:]
+	}
+
+	// Both losses have the gradient (predicted - y) x
+	for j := range x {
+		o.gradient[j] += (predicted - y) * x[j]
+	}
+	o.seen++
+	o.batch++
+	if o.batch == o.options.BatchSize {
+		return o.update()
+	}
+	return nil
+}
+
+// update steps the coefficients along the mean gradient of the batch
+func (o *OnlineSGD) update() error {
+	largest, err := o.step(o.coeffs)
+	if err != nil {
+		return err
+	}
+	for j := range o.gradient {
+		o.gradient[j] = 0
+	}
+	o.batch = 0
+	o.updates++
+	if largest <= o.options.Tolerance {
+		o.stable++
+	} else {
+		o.stable = 0
+	}
+	return nil
+}
+
+// step steps coeffs along the mean gradient of the points of the batch and
+// returns the largest change of a coefficient
+func (o *OnlineSGD) step(coeffs []float64) (float64, error) {
+	rate := o.options.LearningRate /
+		(1 + o.options.Decay*float64(o.updates))
+	largest := 0.0
+	for j := range coeffs {
+		g := o.gradient[j] / float64(o.batch)
+		if j > 0 {
+			g += o.options.L2 * coeffs[j]
+		}
+		coeffs[j] -= rate * g
+		if math.IsNaN(coeffs[j]) || math.IsInf(coeffs[j], 0) {
+			return 0, errors.New("gradient descent diverged, the learning " +
+				"rate may be too large")
+		}
+		largest = math.Max(largest, math.Abs(rate*g))
+	}
+	return largest, nil
+}
+
+// Seen returns the number of points added
+func (o *OnlineSGD) Seen() int {
+	return o.seen
+}
+
+// Converged tells if the coefficients stopped changing
+func (o *OnlineSGD) Converged() bool {
+	return o.options.Tolerance > 0 && o.stable >= o.options.Patience
+}
+
+// Metric returns the accuracy of a logistic regression or the mean squared
+// error of a linear regression on the last Window points, and false until
+// Window points were added
+func (o *OnlineSGD) Metric() (float64, bool) {
+	if len(o.scores) < o.options.Window {
+		return 0, false
+	}
+	return mean(o.scores), true
+}
+
+// TargetReached tells if the metric reached the Target
+func (o *OnlineSGD) TargetReached() bool {
+	m, ok := o.Metric()
+	if !ok || o.options.Target == 0 {
+		return false
+	}
+	if o.options.Logistic {
+		return m >= o.options.Target
+	}
+	return m <= o.options.Target
+}
+
+// Done tells if the training can stop, having converged or reached its target
+func (o *OnlineSGD) Done() bool {
+	return o.Converged() || o.TargetReached()
+}
+
+// Model returns the current model, a *LinearModel or a *LogisticModel. The
+// points of a batch that isn't full yet are learnt by the model returned, but
+// the batch is left to be filled by the next points.
+func (o *OnlineSGD) Model() (Model, error) {
+	if o.coeffs == nil {
+		return nil, errors.New("no points to train on")
+	}
+	coeffs := append([]float64{}, o.coeffs...)
+	if o.batch > 0 {
+		if _, err := o.step(coeffs); err != nil {
+			return nil, err
+		}
+	}
+	if o.options.Logistic {
+		return &LogisticModel{Columns: o.columns, Coefficients: coeffs,
+			Labels: o.labels, Iterations: o.updates,
+			Converged: o.Converged()}, nil
+	}
+	return newLinearModel(&o.columns, coeffs)
+}
+
+// SGDTrainer trains a linear or logistic regression by stochastic gradient
+// descent over Epochs shuffled passes on the points, stopping early once done.
+// Its parameters are the SGDOptions loss, squared by default or log for a
+// logistic regression, learningRate, decay, batchSize, l2, tolerance,
+// patience, target and window, the negative and positive labels, epochs, 10 by
+// default, and the shuffling seed.
+type SGDTrainer struct {
+	Options SGDOptions
+	Epochs  int
+	Seed    int64
+}
+
+func newSGDTrainer(params Params) (Trainer, error) {
+	err := params.Check("loss", "learningRate", "decay", "batchSize", "l2",
+		"tolerance", "patience", "target", "window", "negative", "positive",
+		"epochs", "seed")
+	if err != nil {
+		return nil, err
+	}
+	t := &SGDTrainer{}
+	o := &t.Options
+	switch loss := params["loss"]; loss {
+	case "", "squared":
+	case "log":
+		o.Logistic = true
+	default:
+		return nil, fmt.Errorf("unknown loss %q, expected squared or log",
+			loss)
+	}
+	labels := LabelMapping{}
+	var seed int
+	floats := []struct {
+		key string
+		v   *float64
+		def float64
+	}{{"learningRate", &o.LearningRate, 0}, {"decay", &o.Decay, 0},
+		{"l2", &o.L2, 0}, {"tolerance", &o.Tolerance, 0},
+		{"target", &o.Target, 0}, {"negative", &labels.Negative, 0},
+		{"positive", &labels.Positive, 1}}
+	for _, f := range floats {
+		if *f.v, err = params.Float(f.key, f.def); err != nil {
+			return nil, err
+		}
+	}
+	ints := []struct {
+		key string
+		v   *int
+		def int
+	}{{"batchSize", &o.BatchSize, 0}, {"patience", &o.Patience, 0},
+		{"window", &o.Window, 0}, {"epochs", &t.Epochs, 10},
+		{"seed", &seed, 0}}
+	for _, i := range ints {
+		if *i.v, err = params.Int(i.key, i.def); err != nil {
+			return nil, err
+		}
+	}
+	if o.LearningRate < 0 || o.Decay < 0 || o.L2 < 0 || o.Tolerance < 0 ||
+		o.BatchSize < 0 || o.Patience < 0 || o.Window < 0 {
+		return nil, errors.New("learningRate, decay, l2, tolerance, " +
+			"batchSize, patience and window can't be negative")
+	}
+	if t.Epochs < 1 {
+		return nil, errors.New("epochs must be positive")
+	}
+	o.Labels = &labels
+	t.Seed = int64(seed)
+	return t, nil
+}
+
+// Train implements Trainer
+func (t *SGDTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	o := NewOnlineSGD(columns, &t.Options)
+	r := rand.New(rand.NewSource(t.Seed))
+	for epoch := 0; epoch < t.Epochs && !o.Done(); epoch++ {
+		for _, i := range r.Perm(len(points)) {
+			if err := o.Add(&points[i]); err != nil {
+				return nil, err
+			}
+			if o.Done() {
+				break
+			}
+		}
+	}
+	return o.Model()
+}
+
+// decodeSGDModel decodes the linear or logistic model of an SGDTrainer,
+// logistic models having labels
+func decodeSGDModel(data []byte) (Model, error) {
+	var probe struct {
+		Labels *LabelMapping
+	}
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, errors.New("couldn't decode sgd model: " + err.Error())
+	}
+	if probe.Labels != nil {
+		return decodeLogisticModel(data)
+	}
+	return decodeLinearModel(data)
+}
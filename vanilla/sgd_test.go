@@ -0,0 +1,216 @@
+package vanilla_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSGDTrainer(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      500,
+		Intercept: 1,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 2, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -3, Min: -1, Max: 1},
+		},
+		Noise: 0.1,
+		Seed:  3,
+	})
+	require.Nil(t, err)
+	ols, err := train(t, "ols", nil, d.Points)
+	require.Nil(t, err)
+	m, err := train(t, "sgd", vanilla.Params{"learningRate": "0.1",
+		"decay": "0.01", "batchSize": "5", "epochs": "50"}, d.Points)
+	require.Nil(t, err)
+	for j, c := range ols.Coefficients {
+		require.InDelta(t, c, m.Coefficients[j], 0.05)
+	}
+
+	_, err = vanilla.NewTrainer("sgd", vanilla.Params{"loss": "hinge"})
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("sgd", vanilla.Params{"epochs": "0"})
+	require.NotNil(t, err)
+	for _, key := range []string{"decay", "l2", "tolerance", "patience",
+		"window"} {
+		_, err = vanilla.NewTrainer("sgd", vanilla.Params{key: "-1"})
+		require.NotNil(t, err, key)
+	}
+}
+
+func TestOnlineSGD(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:  2000,
+		Model: vanilla.Logistic,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 6, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -4, Min: -1, Max: 1},
+		},
+		Seed: 4,
+	})
+	require.Nil(t, err)
+	o := vanilla.NewOnlineSGD(nil, &vanilla.SGDOptions{Logistic: true,
+		LearningRate: 0.5, Target: 0.8, Window: 50})
+	_, err = o.Model()
+	require.NotNil(t, err)
+	for i := range d.Points {
+		require.Nil(t, o.Add(&d.Points[i]))
+		if o.Done() {
+			break
+		}
+	}
+	// The target is reached long before every point was read
+	require.True(t, o.TargetReached())
+	require.True(t, o.Seen() < len(d.Points)/2)
+	accuracy, ok := o.Metric()
+	require.True(t, ok)
+	require.True(t, accuracy >= 0.8)
+
+	model, err := o.Model()
+	require.Nil(t, err)
+	logistic := model.(*vanilla.LogisticModel)
+	require.True(t, logistic.Coefficients[1] > 0)
+	require.True(t, logistic.Coefficients[2] < 0)
+	data, err := model.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("sgd", data)
+	require.Nil(t, err)
+	require.Equal(t, model, decoded)
+
+	require.Equal(t, vanilla.ErrFieldsCount, o.Add(&vanilla.MlDataPoint{}))
+	require.NotNil(t, o.Add(&vanilla.MlDataPoint{Label: 2,
+		Variables: []float64{0, 0}}))
+
+	// The coefficients of a linear regression converge
+	o = vanilla.NewOnlineSGD(nil, &vanilla.SGDOptions{LearningRate: 0.1,
+		Decay: 1, Tolerance: 1e-3})
+	d = linearDataset(t)
+	for i := 0; i < 20*len(d.Points) && !o.Done(); i++ {
+		p := d.Points[i%len(d.Points)]
+		require.Nil(t, o.Add(&vanilla.MlDataPoint{Label: p.Label,
+			Variables: []float64{p.Variables[0] / 10}}))
+	}
+	require.True(t, o.Converged())
+	model, err = o.Model()
+	require.Nil(t, err)
+	decoded, err = vanilla.DecodeModel("sgd", mustMarshal(t, model))
+	require.Nil(t, err)
+	require.IsType(t, &vanilla.LinearModel{}, decoded)
+}
+
+func mustMarshal(t *testing.T, m vanilla.Model) []byte {
+	data, err := m.MarshalBinary()
+	require.Nil(t, err)
+	return data
+}
+
+func TestSGDPartialBatch(t *testing.T) {
+	points := []vanilla.MlDataPoint{{Label: 1, Variables: []float64{1}},
+		{Label: 2, Variables: []float64{2}}, {Label: 3, Variables: []float64{3}}}
+	// The points of a batch that isn't full are learnt by the model
+	o := vanilla.NewOnlineSGD(nil, &vanilla.SGDOptions{LearningRate: 0.1,
+		BatchSize: 2})
+	for i := range points {
+		require.Nil(t, o.Add(&points[i]))
+	}
+	model, err := o.Model()
+	require.Nil(t, err)
+	full := vanilla.NewOnlineSGD(nil, &vanilla.SGDOptions{LearningRate: 0.1,
+		BatchSize: 2})
+	for i := range points[:2] {
+		require.Nil(t, full.Add(&points[i]))
+	}
+	learnt, err := full.Model()
+	require.Nil(t, err)
+	require.NotEqual(t, learnt.(*vanilla.LinearModel).Coefficients,
+		model.(*vanilla.LinearModel).Coefficients)
+	// Asking for the model doesn't end the batch
+	require.Nil(t, o.Add(&points[0]))
+	again, err := o.Model()
+	require.Nil(t, err)
+	require.NotEqual(t, model.(*vanilla.LinearModel).Coefficients,
+		again.(*vanilla.LinearModel).Coefficients)
+
+	trainer, err := vanilla.NewTrainer("sgd", vanilla.Params{
+		"batchSize": "4", "epochs": "1", "learningRate": "0.1"})
+	require.Nil(t, err)
+	model, err = trainer.Train(points, nil)
+	require.Nil(t, err)
+	require.NotEqual(t, []float64{0, 0},
+		model.(*vanilla.LinearModel).Coefficients)
+}
+
+func TestOnlineModel(t *testing.T) {
+	s := &vanilla.MlSimulation{Online: true, Trainer: "logistic"}
+	_, err := s.NewOnlineTrainer(nil)
+	require.NotNil(t, err)
+	s.Trainer, s.TrainerParams = "sgd", "learningRate=0.05"
+	o, err := s.NewOnlineTrainer(&vanilla.Columns{Label: "y",
+		Features: []string{"x1", "x2", "x3"}})
+	require.Nil(t, err)
+	d := linearDataset(t)
+	for i := 0; i < 10; i++ {
+		require.Nil(t, o.Add(&d.Points[i]))
+	}
+	saved, err := s.OnlineModel(o, nil)
+	require.Nil(t, err)
+	require.Equal(t, 10, saved.Metadata.TrainingPoints)
+	require.Equal(t, "y", saved.Columns.Label)
+	require.Equal(t, vanilla.Params{"learningRate": "0.05"}, saved.Params)
+}
+
+func TestTrainOnlineSorted(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:  2000,
+		Model: vanilla.Logistic,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "x1", Coefficient: 6, Min: -1, Max: 1},
+			{Name: "x2", Coefficient: -4, Min: -1, Max: 1},
+		},
+		Seed: 4,
+	})
+	require.Nil(t, err)
+	// The points are sorted by label, as the rows of many datasets are
+	sort.SliceStable(d.Points, func(i, j int) bool {
+		return d.Points[i].Label < d.Points[j].Label
+	})
+	s := &vanilla.MlSimulation{Online: true, Trainer: "sgd",
+		TrainerParams: "loss=log,learningRate=0.5,target=0.8,window=50,seed=3"}
+	o, err := s.NewOnlineTrainer(nil)
+	require.Nil(t, err)
+	labels := map[float64]int{}
+	err = s.TrainOnline(o, len(d.Points),
+		func(i int) (*vanilla.MlDataPoint, error) {
+			labels[d.Points[i].Label]++
+			return &d.Points[i], nil
+		})
+	require.Nil(t, err)
+	require.True(t, o.TargetReached())
+	require.True(t, o.Seen() < len(d.Points))
+	// Both labels were learnt before the target was reached
+	require.True(t, labels[0] > 10 && labels[1] > 10)
+	model, err := o.Model()
+	require.Nil(t, err)
+	logistic := model.(*vanilla.LogisticModel)
+	require.True(t, logistic.Coefficients[1] > 0)
+	require.True(t, logistic.Coefficients[2] < 0)
+
+	// The order is seeded
+	again, err := s.NewOnlineTrainer(nil)
+	require.Nil(t, err)
+	require.Nil(t, s.TrainOnline(again, len(d.Points),
+		func(i int) (*vanilla.MlDataPoint, error) {
+			return &d.Points[i], nil
+		}))
+	require.Equal(t, o.Seen(), again.Seen())
+
+	failure := errors.New("read failure")
+	err = s.TrainOnline(again, len(d.Points),
+		func(i int) (*vanilla.MlDataPoint, error) {
+			return nil, failure
+		})
+	require.Equal(t, failure, err)
+}
@@ -11,9 +11,6 @@ import (
 	"github.com/dedis/onet/log"
 	"github.com/dedis/cothority/calypso"
 	"github.com/dedis/student_18_ml/vanilla"
-	"github.com/dedis/cothority"
-	"encoding/json"
-	"github.com/dedis/onet/simul/monitor"
 )
 
 func init() {
@@ -108,120 +105,10 @@ func (s *VanillaSimulation) Run(config *onet.SimulationConfig) error {
 		return errors.New("couldn't create Calypso client: " + err.Error())
 	}
 
-	//Load the dataset records
-	log.Print("Reading dataset from ", s.Dataset)
-	records, err := vanilla.GetDataPointsFromCSV(s.Dataset)
+	err = s.RunPipeline(consumer)
 	if err != nil{
-		return errors.New("couldn't read dataset: " + err.Error())
-	} else {
-		log.Print("Dataset has ", len(records), " instances")
-	}
-	//Create data providers and associate identities
-	providers := make([]darc.Signer, len(records))
-
-	for i,_ := range providers {
-		providers[i] = darc.NewSignerEd25519(nil, nil)
-	}
-	log.Print("Created identities for data providers")
-
-	providers_ids := vanilla.GetIdentitiesFromSigners(providers)
-	consumer_id := consumer.Identity()
-
-	secrets, darcs, err := vanilla.AssociateProviders(
-		providers_ids, records, "BreastDancerData", &consumer_id)
-	if err != nil{
-		return errors.New("Couldn't associate data to providers: " + err.Error())
-	} else{
-		log.Print("Assigned data point to each provider and created darcs")
-	}
-
-	write_insts := make([]byzcoin.InstanceID, len(records))
-	write_proofs := make([]*byzcoin.Proof, len(records))
-	read_proofs := make([]*byzcoin.Proof, len(records))
-	read_insts := make([]byzcoin.InstanceID, len(records))
-
-	prepare_t := monitor.NewTimeMeasure("prepare")
-	//uint64 counter := 0
-	//counter = counter  + 1
-	for i, secret := range *secrets {
-		s.Client.SpawnDarc(s.Admin, uint64(i+1), s.Gm.GenesisDarc, *darcs[i], 4)
-		log.Printf("Darc %d spawned", i)
-		write := calypso.NewWrite(cothority.Suite,
-			s.LtsReply.LTSID,
-			darcs[i].GetBaseID(),
-			s.LtsReply.X,
-			secret)
-		reply, err := s.Client.AddWrite(write, providers[i], uint64(1), *darcs[i], 0)
-		if err != nil{
-			return errors.New("couldn't spawn write instance: " + err.Error())
-		}
-		write_insts[i] = reply.InstanceID
-	}
-
-	//Wait for all write instructions to be executed
-	for i, _ := range write_insts {
-		prf, err := s.Client.WaitProof(write_insts[i], s.Gm.BlockInterval, nil)
-		if err != nil{
-			return errors.New("couldn't get write proof: " + err.Error())
-		}
-		write_proofs[i] = prf
-	}
-	prepare_t.Record()
-
-	pipeline_t := monitor.NewTimeMeasure("pipeline")
-	for i, darc := range darcs {
-		read_spawn_t := monitor.NewTimeMeasure("read_spawn")
-		reply, err := s.Client.AddRead(write_proofs[i], consumer,
-			uint64(i+1), *darc, 0)
-		if err != nil{
-			return errors.New("couldn't spawn read instance: " + err.Error())
-		}
-		read_insts[i] = reply.InstanceID
-		read_spawn_t.Record()
-	}
-
-	//Wait for all read instructions to be executed
-	for i, _ := range read_insts {
-		read_proof_t := monitor.NewTimeMeasure("read_proof")
-		prf, err := s.Client.WaitProof(read_insts[i], s.Gm.BlockInterval, nil)
-		if err != nil{
-			return errors.New("couldn't get read proof: " + err.Error())
-		}
-		read_proofs[i] = prf
-		read_proof_t.Record()
-	}
-
-	points := make([]vanilla.MlDataPoint, len(records))
-
-	for i, _ := range points {
-		decrypt_t := monitor.NewTimeMeasure("decrypt")
-		reply, err := s.Client.DecryptKey(&calypso.DecryptKey{
-			*read_proofs[i], *write_proofs[i]})
-		if err != nil{
-			return errors.New("couldn't decrypt key: " + err.Error())
-		}
-		if !reply.X.Equal(s.LtsReply.X) {
-			return errors.New("LTS didn't match")
-		}
-		data_bytes, err := calypso.DecodeKey(cothority.Suite, s.LtsReply.X,
-			reply.Cs, reply.XhatEnc, consumer.Ed25519.Secret)
-		if err != nil{
-			return errors.New("couldn't decode data point: " + err.Error())
-		}
-		err = json.Unmarshal(data_bytes, &points[i])
-		if err != nil{
-			return errors.New("couldn't cast data point from binary: " + err.Error())
-		}
-		decrypt_t.Record()
-	}
-
-	r, err := vanilla.VanillaTrainRegressionModel(points)
-	if err != nil{
-		return errors.New("couldn't train model: " + err.Error())
-	} else {
-		log.Printf("Training finished, formula is: %s", r.Formula)
+		return err
 	}
-	pipeline_t.Record()
 	// We wait a bit before closing because c.GetProof is sent to the
 	// leader, but at this point some of the children might still be doing
 	// updateCollection. If we stop the simulation immediately, then the
@@ -0,0 +1,217 @@
+package vanilla
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strings"
+)
+
+// DataSource iterates over the data points of a dataset without holding them
+// all in memory
+type DataSource interface {
+	// Next returns the next data point, and io.EOF once all the points have
+	// been returned
+	Next() (*MlDataPoint, error)
+	// Columns names the label and the features of the points
+	Columns() Columns
+	// Rejected returns the rows skipped so far in lenient mode or dropped
+	// because of missing cells
+	Rejected() []*LoadError
+	// Imputed returns the cells that were imputed in the last point returned
+	// by Next
+	Imputed() []ImputedCell
+	// Close releases the underlying file
+	Close() error
+}
+
+// recordReader reads the records of a dataset file as strings
+type recordReader interface {
+	// Headers returns the names of the columns
+	Headers() []string
+	// Read returns the next record and its row number, and io.EOF after the
+	// last record. Errors reading the file itself, as opposed to malformed
+	// rows, are readFailures.
+	Read() ([]string, int, error)
+	Close() error
+}
+
+// readFailure is an error reading a dataset file, which can't be skipped
+// like a malformed row
+type readFailure struct {
+	err error
+}
+
+func (f *readFailure) Error() string {
+	return f.err.Error()
+}
+
+// typedRecords is implemented by the recordReaders of formats declaring the
+// type of their columns
+type typedRecords interface {
+	// Schemas returns the schema of every column of the header
+	Schemas() []ColumnSchema
+}
+
+// recordSource is a DataSource encoding the records of a recordReader
+type recordSource struct {
+	records  recordReader
+	encoder  *recordEncoder
+	opts     *LoadOptions
+	rejected []*LoadError
+	imputed  []ImputedCell
+	// row is the row number of the last point returned by Next
+	row int
+}
+
+// newRecordSource opens a dataset file with open and selects its columns.
+// The file is read once more beforehand by the mean and median missing
+// strategies to compute their statistics.
+func newRecordSource(fileName string, opts *LoadOptions,
+	open func(string) (recordReader, error)) (*recordSource, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	opts, err := opts.withImputationStats(fileName, open)
+	if err != nil {
+		return nil, err
+	}
+	records, err := open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := newRecordEncoder(fileName, records, opts)
+	if err != nil {
+		records.Close()
+		return nil, err
+	}
+	if opts.Scaler != nil {
+		if err := opts.Scaler.check(encoder.columns.Features); err != nil {
+			records.Close()
+			return nil, &LoadError{File: fileName, Err: err}
+		}
+	}
+	return &recordSource{records: records, encoder: encoder, opts: opts}, nil
+}
+
+// Next implements DataSource
+func (s *recordSource) Next() (*MlDataPoint, error) {
+	for {
+		record, row, err := s.records.Read()
+		if err == io.EOF {
+			return nil, err
+		}
+		var label float64
+		var variables []float64
+		if err == nil {
+			label, variables, s.imputed, err = s.encoder.encode(row, record)
+		} else if failure, ok := err.(*readFailure); ok {
+			return nil, &LoadError{File: s.encoder.file, Row: row,
+				Err: failure.err}
+		} else {
+			err = &LoadError{File: s.encoder.file, Row: row, Err: err}
+		}
+		if err != nil {
+			if !s.opts.Lenient && !s.opts.drops(err.(*LoadError)) {
+				return nil, err
+			}
+			s.rejected = append(s.rejected, err.(*LoadError))
+			continue
+		}
+		point := &MlDataPoint{Label: label, Variables: variables}
+		s.row = row
+		if s.opts.Scaler != nil {
+			// The features were checked against the scaler when opening
+			s.opts.Scaler.Scale(point)
+		}
+		return point, nil
+	}
+}
+
+// Columns implements DataSource
+func (s *recordSource) Columns() Columns {
+	return s.encoder.columns
+}
+
+// Rejected implements DataSource
+func (s *recordSource) Rejected() []*LoadError {
+	return s.rejected
+}
+
+// Imputed implements DataSource
+func (s *recordSource) Imputed() []ImputedCell {
+	return s.imputed
+}
+
+// Close implements DataSource
+func (s *recordSource) Close() error {
+	return s.records.Close()
+}
+
+// CSVSource is a DataSource reading a csv file with a header
+type CSVSource struct {
+	*recordSource
+}
+
+// NewCSVSource opens a csv dataset, whose columns are selected by opts,
+// which may be nil
+func NewCSVSource(fileName string, opts *LoadOptions) (*CSVSource, error) {
+	source, err := newRecordSource(fileName, opts, openCSV)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVSource{source}, nil
+}
+
+// csvRecords reads the records of a csv file
+type csvRecords struct {
+	file    *os.File
+	reader  *csv.Reader
+	headers []string
+	row     int
+}
+
+// openCSV opens a csv dataset and reads its header
+func openCSV(fileName string) (recordReader, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, &LoadError{File: fileName, Err: err}
+	}
+	//Create a new csv reader, rows are checked against the header later
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	headers, err := reader.Read()
+	if err != nil {
+		file.Close()
+		if err == io.EOF {
+			return nil, &LoadError{File: fileName, Err: ErrNoHeader}
+		}
+		return nil, &LoadError{File: fileName, Row: 1, Err: err}
+	}
+	for i := range headers {
+		headers[i] = strings.TrimSpace(headers[i])
+	}
+	return &csvRecords{file: file, reader: reader, headers: headers,
+		row: 1}, nil
+}
+
+func (r *csvRecords) Headers() []string {
+	return r.headers
+}
+
+func (r *csvRecords) Read() ([]string, int, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, 0, err
+	}
+	if _, ok := err.(*csv.ParseError); err != nil && !ok {
+		return nil, r.row, &readFailure{err}
+	}
+	r.row++
+	return record, r.row, err
+}
+
+func (r *csvRecords) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,78 @@
+package vanilla_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVSource(t *testing.T) {
+	policy := &vanilla.MissingPolicy{Strategy: vanilla.FillConstant,
+		Tokens: []string{"NA"}}
+	source, err := vanilla.NewCSVSource("tests/incomplete.csv",
+		&vanilla.LoadOptions{Missing: policy, Lenient: true})
+	require.Nil(t, err)
+	defer source.Close()
+	require.Equal(t, "label", source.Columns().Label)
+
+	point, err := source.Next()
+	require.Nil(t, err)
+	require.Equal(t, 1.0, point.Label)
+	require.Equal(t, []float64{1, 2, 0}, point.Variables)
+	require.Equal(t, []vanilla.ImputedCell{{2, "field3", 0}}, source.Imputed())
+
+	point, err = source.Next()
+	require.Nil(t, err)
+	require.Equal(t, 2.0, point.Label)
+	require.Equal(t, 0, len(source.Rejected()))
+
+	// The third row has no label
+	point, err = source.Next()
+	require.Nil(t, err)
+	require.Equal(t, []float64{7, 10, 7}, point.Variables)
+	require.Equal(t, 0, len(source.Imputed()))
+	require.Equal(t, 1, len(source.Rejected()))
+	require.Equal(t, 4, source.Rejected()[0].Row)
+
+	_, err = source.Next()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestCSVSourceStrict(t *testing.T) {
+	source, err := vanilla.NewCSVSource("tests/malformed.csv", nil)
+	require.Nil(t, err)
+	defer source.Close()
+	_, err = source.Next()
+	require.Nil(t, err)
+	_, err = source.Next()
+	require.NotNil(t, err)
+	require.Equal(t, 3, err.(*vanilla.LoadError).Row)
+}
+
+func TestSourceReadFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "source")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	// A line longer than the limit of the reader can't be read, which isn't
+	// a row that lenient mode skips
+	fileName := filepath.Join(dir, "long.jsonl")
+	require.Nil(t, ioutil.WriteFile(fileName, []byte(`{"x": 1, "label": 2}
+{"x": "`+strings.Repeat("1", 17*1024*1024)+`", "label": 2}
+`), 0644))
+	source, err := vanilla.NewJSONLinesSource(fileName,
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	defer source.Close()
+	_, err = source.Next()
+	require.Nil(t, err)
+	_, err = source.Next()
+	require.NotNil(t, err)
+	require.NotEqual(t, io.EOF, err)
+	require.Equal(t, 0, len(source.Rejected()))
+}
@@ -0,0 +1,223 @@
+package vanilla
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/dedis/student_18_ml/vanilla/metrics"
+	"github.com/sajari/regression"
+)
+
+// SplitOptions tell how data points are split for evaluation
+type SplitOptions struct {
+	// Seed seeds the shuffling of the points
+	Seed int64
+	// Source, when set, is used instead of Seed
+	Source rand.Source
+	// Stratified keeps the proportion of every label in each part, the
+	// labels being classes
+	Stratified bool
+}
+
+// random returns the random generator shuffling the points
+func (opts *SplitOptions) random() *rand.Rand {
+	if opts == nil {
+		return rand.New(rand.NewSource(0))
+	}
+	if opts.Source != nil {
+		return rand.New(opts.Source)
+	}
+	return rand.New(rand.NewSource(opts.Seed))
+}
+
+// Split holds the indices of the training and test points of a dataset
+type Split struct {
+	Train []int
+	Test  []int
+}
+
+// DataPoints returns the training and test points of a split
+func (s *Split) DataPoints(points regression.DataPoints) (
+	train regression.DataPoints, test regression.DataPoints) {
+	for _, i := range s.Train {
+		train = append(train, points[i])
+	}
+	for _, i := range s.Test {
+		test = append(test, points[i])
+	}
+	return train, test
+}
+
+// MlDataPoints returns the training and test points of a split
+func (s *Split) MlDataPoints(points []MlDataPoint) (train []MlDataPoint,
+	test []MlDataPoint) {
+	for _, i := range s.Train {
+		train = append(train, points[i])
+	}
+	for _, i := range s.Test {
+		test = append(test, points[i])
+	}
+	return train, test
+}
+
+// Labels returns the labels of data points
+func Labels(points regression.DataPoints) []float64 {
+	labels := make([]float64, len(points))
+	for i, p := range points {
+		labels[i] = p.Observed
+	}
+	return labels
+}
+
+// MlLabels returns the labels of data points
+func MlLabels(points []MlDataPoint) []float64 {
+	labels := make([]float64, len(points))
+	for i, p := range points {
+		labels[i] = p.Label
+	}
+	return labels
+}
+
+// shuffledGroups returns the shuffled indices of the points, in a single
+// group or in a group per label when stratified
+func shuffledGroups(labels []float64, opts *SplitOptions) [][]int {
+	r := opts.random()
+	var groups [][]int
+	if opts != nil && opts.Stratified {
+		byLabel := make(map[float64][]int)
+		var classes []float64
+		for i, l := range labels {
+			if _, ok := byLabel[l]; !ok {
+				classes = append(classes, l)
+			}
+			byLabel[l] = append(byLabel[l], i)
+		}
+		// Map iteration isn't deterministic, the classes are sorted
+		sort.Float64s(classes)
+		for _, c := range classes {
+			groups = append(groups, byLabel[c])
+		}
+	} else {
+		group := make([]int, len(labels))
+		for i := range group {
+			group[i] = i
+		}
+		groups = [][]int{group}
+	}
+	for _, group := range groups {
+		r.Shuffle(len(group), func(i, j int) {
+			group[i], group[j] = group[j], group[i]
+		})
+	}
+	return groups
+}
+
+// TrainTestSplit splits points, given by their labels, holding out
+// testFraction of them for testing. opts may be nil.
+func TrainTestSplit(labels []float64, testFraction float64,
+	opts *SplitOptions) (*Split, error) {
+	if testFraction <= 0 || testFraction >= 1 {
+		return nil, errors.New("test fraction must be between 0 and 1")
+	}
+	split := &Split{}
+	for _, group := range shuffledGroups(labels, opts) {
+		n := int(math.Round(testFraction * float64(len(group))))
+		split.Test = append(split.Test, group[:n]...)
+		split.Train = append(split.Train, group[n:]...)
+	}
+	if len(split.Train) == 0 || len(split.Test) == 0 {
+		return nil, errors.New("not enough points to split")
+	}
+	sort.Ints(split.Train)
+	sort.Ints(split.Test)
+	return split, nil
+}
+
+// KFold splits points, given by their labels, in k folds. Every point is
+// tested in exactly one of the returned splits. opts may be nil.
+func KFold(labels []float64, k int, opts *SplitOptions) ([]*Split, error) {
+	if k < 2 || k > len(labels) {
+		return nil, errors.New("k must be between 2 and the number of points")
+	}
+	folds := make([][]int, k)
+	next := 0
+	for _, group := range shuffledGroups(labels, opts) {
+		// Groups continue filling the folds where the previous one stopped,
+		// so that the folds have the same size
+		for _, i := range group {
+			folds[next] = append(folds[next], i)
+			next = (next + 1) % k
+		}
+	}
+	splits := make([]*Split, k)
+	for f := range folds {
+		splits[f] = &Split{Test: folds[f]}
+		for g := range folds {
+			if g != f {
+				splits[f].Train = append(splits[f].Train, folds[g]...)
+			}
+		}
+		sort.Ints(splits[f].Train)
+		sort.Ints(splits[f].Test)
+	}
+	return splits, nil
+}
+
+// FoldMetrics are the metrics of a model trained on a fold
+type FoldMetrics struct {
+	Train int
+	Test  int
+	// MSE, RMSE and MAE are the mean squared, root mean squared and mean
+	// absolute errors on the test points
+	MSE  float64
+	RMSE float64
+	MAE  float64
+	// R2 is the coefficient of determination on the test points
+	R2 float64
+}
+
+// CrossValidate trains a regression model with
+// VanillaTrainNamedRegressionModel on each of k folds of points and returns
+// its metrics on the points held out. columns and opts may be nil.
+func CrossValidate(points []MlDataPoint, columns *Columns, k int,
+	opts *SplitOptions) ([]FoldMetrics, error) {
+	splits, err := KFold(MlLabels(points), k, opts)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]FoldMetrics, k)
+	for f, split := range splits {
+		train, test := split.MlDataPoints(points)
+		r, err := VanillaTrainNamedRegressionModel(train, columns)
+		if err != nil {
+			return nil, errors.New("couldn't train fold: " + err.Error())
+		}
+		results[f], err = regressionMetrics(r, test)
+		if err != nil {
+			return nil, errors.New("couldn't evaluate fold: " + err.Error())
+		}
+		results[f].Train = len(train)
+	}
+	return results, nil
+}
+
+// regressionMetrics evaluates a regression model on test points
+func regressionMetrics(r *regression.Regression, test []MlDataPoint) (
+	FoldMetrics, error) {
+	predicted := make([]float64, len(test))
+	for i, p := range test {
+		var err error
+		predicted[i], err = r.Predict(p.Variables)
+		if err != nil {
+			return FoldMetrics{}, err
+		}
+	}
+	m, err := metrics.ComputeRegression(MlLabels(test), predicted)
+	if err != nil {
+		return FoldMetrics{}, err
+	}
+	return FoldMetrics{Test: m.Count, MSE: m.MSE, RMSE: m.RMSE, MAE: m.MAE,
+		R2: m.R2}, nil
+}
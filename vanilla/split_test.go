@@ -0,0 +1,89 @@
+package vanilla_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+// classPoints returns n points of class 0 and n/4 points of class 1
+func classPoints(n int) []vanilla.MlDataPoint {
+	var points []vanilla.MlDataPoint
+	for i := 0; i < n+n/4; i++ {
+		label := 0.0
+		if i >= n {
+			label = 1
+		}
+		x := float64(i)
+		points = append(points, vanilla.MlDataPoint{Label: label,
+			Variables: []float64{x, x * x / 10}})
+	}
+	return points
+}
+
+func TestTrainTestSplit(t *testing.T) {
+	labels := vanilla.MlLabels(classPoints(40))
+	split, err := vanilla.TrainTestSplit(labels, 0.2,
+		&vanilla.SplitOptions{Seed: 1})
+	require.Nil(t, err)
+	require.Equal(t, 40, len(split.Train))
+	require.Equal(t, 10, len(split.Test))
+
+	// The same seed gives the same split
+	again, err := vanilla.TrainTestSplit(labels, 0.2,
+		&vanilla.SplitOptions{Source: rand.NewSource(1)})
+	require.Nil(t, err)
+	require.Equal(t, split, again)
+
+	split, err = vanilla.TrainTestSplit(labels, 0.2,
+		&vanilla.SplitOptions{Seed: 2, Stratified: true})
+	require.Nil(t, err)
+	ones := 0
+	for _, i := range split.Test {
+		ones += int(labels[i])
+	}
+	require.Equal(t, 2, ones)
+
+	_, err = vanilla.TrainTestSplit(labels, 1, nil)
+	require.NotNil(t, err)
+}
+
+func TestKFold(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	splits, err := vanilla.KFold(vanilla.Labels(dataset.Points), 5,
+		&vanilla.SplitOptions{Seed: 3})
+	require.Nil(t, err)
+	tested := make(map[int]bool)
+	for _, split := range splits {
+		require.Equal(t, 1, len(split.Test))
+		require.Equal(t, 4, len(split.Train))
+		tested[split.Test[0]] = true
+		train, test := split.DataPoints(dataset.Points)
+		require.Equal(t, 4, len(train))
+		require.Equal(t, dataset.Points[split.Test[0]], test[0])
+	}
+	require.Equal(t, 5, len(tested))
+
+	_, err = vanilla.KFold(vanilla.Labels(dataset.Points), 6, nil)
+	require.NotNil(t, err)
+}
+
+func TestCrossValidate(t *testing.T) {
+	points := classPoints(40)
+	for i := range points {
+		points[i].Label = 3 + 2*points[i].Variables[0] - points[i].Variables[1]
+	}
+	metrics, err := vanilla.CrossValidate(points, nil, 5,
+		&vanilla.SplitOptions{Seed: 4})
+	require.Nil(t, err)
+	require.Equal(t, 5, len(metrics))
+	for _, m := range metrics {
+		require.Equal(t, 40, m.Train)
+		require.Equal(t, 10, m.Test)
+		require.InDelta(t, 0, m.RMSE, 1e-6)
+		require.InDelta(t, 1, m.R2, 1e-6)
+	}
+}
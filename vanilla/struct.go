@@ -1,10 +1,16 @@
 package vanilla
 
 import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+
 	"github.com/dedis/cothority/calypso"
 	"github.com/dedis/cothority/darc"
 	"github.com/dedis/cothority/byzcoin"
 	"github.com/dedis/onet"
+	"github.com/dedis/onet/log"
 )
 
 // MlDataPoint is a class containing a training dataPoint.
@@ -17,10 +23,380 @@ type MlDataPoint struct {
 type MlSimulation struct {
 	onet.SimulationBFTree
 	Dataset       string
+	// Format is the format of the dataset, csv, jsonl, libsvm or arff, given
+	// by its extension if empty
+	Format        string
+	// Lenient skips the dataset rows that can't be loaded
+	Lenient       bool
+	// Label is the header of the label column, the last one if empty
+	Label         string
+	// Features and Exclude are comma-separated headers selecting the
+	// feature columns, see LoadOptions
+	Features      string
+	Exclude       string
+	// Schema is the path of a schema file saved by Schema.Save
+	Schema        string
+	// Missing is the MissingStrategy, the rows with missing cells being
+	// rejected if empty. MissingValue is used by the constant strategy,
+	// ImputationStats is the path of the statistics saved by
+	// ImputationStats.Save used by the external strategy and MissingTokens
+	// are comma-separated cell values marking missing cells.
+	Missing         string
+	MissingValue    float64
+	ImputationStats string
+	MissingTokens   string
+	// Scaler is the path of a scaler saved by Scaler.Save, which the data
+	// providers apply to their points
+	Scaler          string
+	// Trainer is the name of the registered trainer used by the consumer,
+	// such as ridge, logistic, naivebayes, kmeans or forest, "ols" if empty,
+	// and TrainerParams are its comma-separated key=value parameters
+	Trainer         string
+	TrainerParams   string
+	// TestFraction of the decrypted points are held out to evaluate the
+	// model, split with SplitSeed, and the evaluation is saved as json to
+	// MetricsFile if set
+	TestFraction    float64
+	SplitSeed       int64
+	MetricsFile     string
+	// ModelFile, if set, is the path the trained model is saved to, with its
+	// scaler and the write instances it was trained on, see LoadModel
+	ModelFile       string
+	// Streaming folds every decrypted point into a GramAccumulator instead
+	// of keeping it, which trains the ols and ridge trainers only, without
+	// held-out points
+	Streaming       bool
+	// Online trains the sgd trainer as the points are read one at a time, in
+	// an order shuffled by its seed, and stops reading once the training
+	// converged or reached its target
+	Online          bool
+	// SearchSpace, if set, are the comma-separated key=value|value|...
+	// parameter values of Trainer cross-validated on the training points
+	// over SearchFolds folds, SearchSamples of them drawn at random if set,
+	// scored by SearchMetric. The model is trained with the best ones and the
+	// search is saved as json to SearchFile if set.
+	SearchSpace     string
+	SearchFolds     int
+	SearchSamples   int
+	SearchMetric    string
+	SearchFile      string
+	// Publish writes the trained model to Calypso under a darc readable by
+	// the comma-separated ModelReaders, "providers" and/or "board" for an
+	// ethics board, the providers if empty
+	Publish         bool
+	ModelReaders    string
 	BlockInterval string
 	Keep          bool
 	*calypso.Client
 	LtsReply      *calypso.CreateLTSReply
 	Admin         darc.Signer
 	Gm            *byzcoin.CreateGenesisBlock
-}
\ No newline at end of file
+}
+
+// LoadOptions returns the dataset loading options set in the simulation
+// config
+func (s *MlSimulation) LoadOptions() (*LoadOptions, error) {
+	opts := &LoadOptions{
+		Lenient:  s.Lenient,
+		Label:    s.Label,
+		Features: splitNames(s.Features),
+		Exclude:  splitNames(s.Exclude),
+		Format:   Format(s.Format),
+	}
+	if s.Schema != "" {
+		var err error
+		opts.Schema, err = LoadSchema(s.Schema)
+		if err != nil {
+			return nil, errors.New("couldn't load schema: " + err.Error())
+		}
+	}
+	if s.Missing != "" {
+		opts.Missing = &MissingPolicy{
+			Strategy: MissingStrategy(s.Missing),
+			Value:    s.MissingValue,
+			Tokens:   splitNames(s.MissingTokens),
+		}
+	}
+	if s.ImputationStats != "" && opts.Missing != nil {
+		var err error
+		opts.Missing.Stats, err = LoadImputationStats(s.ImputationStats)
+		if err != nil {
+			return nil, errors.New("couldn't load imputation statistics: " +
+				err.Error())
+		}
+	} else if opts.Missing != nil && (opts.Missing.Strategy == FillMean ||
+		opts.Missing.Strategy == FillMedian) {
+		// The statistics are computed once, and kept to fill the points
+		// predicted by the trained model
+		stats, err := ComputeImputationStats(s.Dataset, opts,
+			opts.Missing.Strategy)
+		if err != nil {
+			return nil, errors.New("couldn't compute imputation statistics: " +
+				err.Error())
+		}
+		opts.Missing.Strategy = FillExternal
+		opts.Missing.Stats = stats
+	}
+	if s.Scaler != "" {
+		var err error
+		opts.Scaler, err = LoadScaler(s.Scaler)
+		if err != nil {
+			return nil, errors.New("couldn't load scaler: " + err.Error())
+		}
+	}
+	return opts, nil
+}
+
+// splitNames splits a comma-separated list of names
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TrainModel trains the model selected by Trainer on points whose label and
+// features are named by columns, and evaluates it on the TestFraction of the
+// points held out. The points were loaded with opts, which may be nil, and
+// the saved model loads and scales the points it predicts as them, so that it
+// predicts from the original units of the features. A kmeans clustering has
+// no labels to be scored against, so it is neither evaluated nor searched.
+func (s *MlSimulation) TrainModel(points []MlDataPoint, columns *Columns,
+	opts *LoadOptions) (*SavedModel, error) {
+	name := s.Trainer
+	if name == "" {
+		name = "ols"
+	}
+	params, err := ParseParams(s.TrainerParams)
+	if err != nil {
+		return nil, err
+	}
+	trainer, err := NewTrainer(name, params)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := trainer.(*KMeansTrainer); ok && (s.TestFraction > 0 ||
+		s.SearchSpace != "") {
+		return nil, errors.New("kmeans clusters can't be scored against " +
+			"the labels")
+	}
+	train, test := points, []MlDataPoint(nil)
+	if s.TestFraction > 0 {
+		split, err := TrainTestSplit(MlLabels(points), s.TestFraction,
+			&SplitOptions{Seed: s.SplitSeed})
+		if err != nil {
+			return nil, err
+		}
+		train, test = split.MlDataPoints(points)
+	}
+	if s.SearchSpace != "" {
+		params, err = s.searchParams(name, params, train, columns)
+		if err != nil {
+			return nil, err
+		}
+		trainer, err = NewTrainer(name, params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	model, err := trainer.Train(train, columns)
+	if err != nil {
+		return nil, err
+	}
+	saved := s.savedModel(name, params, model, columns, opts, len(train))
+	if len(test) > 0 {
+		// The test points are as scaled as the training points
+		saved.Metadata.Evaluation, err = EvaluateModel(model, test)
+		if err != nil {
+			return nil, errors.New("couldn't evaluate model: " + err.Error())
+		}
+		if s.MetricsFile != "" {
+			err = saved.Metadata.Evaluation.Save(s.MetricsFile)
+			if err != nil {
+				return nil, errors.New("couldn't save metrics: " + err.Error())
+			}
+		}
+	}
+	return saved, nil
+}
+
+// searchParams returns the best parameters of SearchSpace, the others being
+// base
+func (s *MlSimulation) searchParams(name string, base Params,
+	points []MlDataPoint, columns *Columns) (Params, error) {
+	space, err := ParseParamSpace(s.SearchSpace)
+	if err != nil {
+		return nil, err
+	}
+	result, err := SearchHyperparameters(name, space, points, columns,
+		&SearchOptions{Folds: s.SearchFolds, Samples: s.SearchSamples,
+			Metric: s.SearchMetric, Base: base,
+			Split: SplitOptions{Seed: s.SplitSeed}})
+	if err != nil {
+		return nil, errors.New("couldn't search parameters: " + err.Error())
+	}
+	if s.SearchFile != "" {
+		err = result.Save(s.SearchFile)
+		if err != nil {
+			return nil, errors.New("couldn't save search: " + err.Error())
+		}
+	}
+	return result.Best, nil
+}
+
+// NewStreamingAccumulator creates the accumulator of the points folded in
+// when Streaming is set, after checking that Trainer can be fitted on it, so
+// that no point is read in vain
+func (s *MlSimulation) NewStreamingAccumulator() (*GramAccumulator, error) {
+	if _, _, _, err := s.streamedTrainer(); err != nil {
+		return nil, err
+	}
+	return NewGramAccumulator(), nil
+}
+
+// streamedTrainer returns the name, the parameters and the trainer selected
+// by Trainer, which must be fitted on a GramAccumulator
+func (s *MlSimulation) streamedTrainer() (string, Params, Trainer, error) {
+	if s.TestFraction > 0 {
+		return "", nil, nil, errors.New("streamed points can't be held out")
+	}
+	name := s.Trainer
+	if name == "" {
+		name = "ols"
+	}
+	params, err := ParseParams(s.TrainerParams)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	trainer, err := NewTrainer(name, params)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	switch trainer.(type) {
+	case *OLSTrainer, *RidgeTrainer:
+		return name, params, trainer, nil
+	}
+	return "", nil, nil, errors.New("trainer " + name +
+		" can't be trained on streamed points")
+}
+
+// StreamedModel fits the model selected by Trainer on the points folded into
+// an accumulator created by NewStreamingAccumulator. The points were loaded
+// with opts, which may be nil, as in TrainModel.
+func (s *MlSimulation) StreamedModel(a *GramAccumulator, columns *Columns,
+	opts *LoadOptions) (*SavedModel, error) {
+	name, params, trainer, err := s.streamedTrainer()
+	if err != nil {
+		return nil, err
+	}
+	var model *LinearModel
+	switch t := trainer.(type) {
+	case *OLSTrainer:
+		model, err = a.inferredRidgeModel(0, t.Confidence, columns)
+	case *RidgeTrainer:
+		model, err = a.inferredRidgeModel(t.Lambda, t.Confidence, columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.savedModel(name, params, model, columns, opts, a.Count()), nil
+}
+
+// NewOnlineTrainer creates the online training of points named by columns,
+// when Online is set. Trainer must be sgd.
+func (s *MlSimulation) NewOnlineTrainer(columns *Columns) (*OnlineSGD,
+	error) {
+	trainer, err := s.onlineTrainer()
+	if err != nil {
+		return nil, err
+	}
+	return NewOnlineSGD(columns, &trainer.Options), nil
+}
+
+// onlineTrainer returns the sgd trainer selected by Trainer when Online is
+// set
+func (s *MlSimulation) onlineTrainer() (*SGDTrainer, error) {
+	if s.TestFraction > 0 {
+		return nil, errors.New("points trained online can't be held out")
+	}
+	if s.Trainer != "sgd" {
+		return nil, errors.New("only the sgd trainer trains online")
+	}
+	params, err := ParseParams(s.TrainerParams)
+	if err != nil {
+		return nil, err
+	}
+	trainer, err := NewTrainer(s.Trainer, params)
+	if err != nil {
+		return nil, err
+	}
+	return trainer.(*SGDTrainer), nil
+}
+
+// TrainOnline trains o, created by NewOnlineTrainer, on n points read one at
+// a time by read until the training is done. The points are read in an order
+// shuffled with the seed of the trainer, so that points sorted by label don't
+// fill the window of the target with a single label.
+func (s *MlSimulation) TrainOnline(o *OnlineSGD, n int,
+	read func(i int) (*MlDataPoint, error)) error {
+	trainer, err := s.onlineTrainer()
+	if err != nil {
+		return err
+	}
+	for _, i := range rand.New(rand.NewSource(trainer.Seed)).Perm(n) {
+		point, err := read(i)
+		if err != nil {
+			return err
+		}
+		err = o.Add(point)
+		if err != nil {
+			return errors.New("couldn't train model: " + err.Error())
+		}
+		if intermediate, err := o.Model(); err == nil {
+			log.Lvlf2("Model after %d points: %s", o.Seen(),
+				intermediate.Describe())
+		}
+		if o.Done() {
+			break
+		}
+	}
+	return nil
+}
+
+// OnlineModel returns the model trained online so far. The points were
+// loaded with opts, which may be nil, as in TrainModel.
+func (s *MlSimulation) OnlineModel(o *OnlineSGD, opts *LoadOptions) (
+	*SavedModel, error) {
+	model, err := o.Model()
+	if err != nil {
+		return nil, err
+	}
+	params, err := ParseParams(s.TrainerParams)
+	if err != nil {
+		return nil, err
+	}
+	return s.savedModel(s.Trainer, params, model, &o.columns, opts,
+		o.Seen()), nil
+}
+
+// savedModel returns a model trained by the trainer name with params on count
+// points named by columns, which may be nil, and loaded with opts, which may
+// be nil
+func (s *MlSimulation) savedModel(name string, params Params, model Model,
+	columns *Columns, opts *LoadOptions, count int) *SavedModel {
+	saved := &SavedModel{Trainer: name, Params: params, Model: model,
+		Metadata: ModelMetadata{Dataset: s.Dataset, TrainingPoints: count,
+			Trained: time.Now().UTC()}}
+	if columns != nil {
+		saved.Columns = *columns
+	}
+	if opts != nil {
+		saved.Scaler = opts.Scaler
+		saved.Schema = opts.Schema
+		saved.Missing = opts.Missing
+	}
+	return saved
+}
@@ -0,0 +1,179 @@
+package vanilla
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+)
+
+// SyntheticModel is the model generating the labels of a synthetic dataset
+type SyntheticModel string
+
+const (
+	// Linear labels are the linear combination of the features plus noise
+	Linear SyntheticModel = "linear"
+	// Logistic labels are 0 or 1, 1 with the probability given by the
+	// sigmoid of the linear combination of the features plus noise
+	Logistic SyntheticModel = "logistic"
+)
+
+// SyntheticColumn describes a feature column of a synthetic dataset
+type SyntheticColumn struct {
+	Name string
+	// Coefficient is the true coefficient of the column
+	Coefficient float64
+	// Min and Max bound the uniform values of numeric columns, which are in
+	// [0, 1) if both are 0
+	Min float64
+	Max float64
+	// Categories make the column categorical, uniformly drawn and encoded by
+	// the index of their category
+	Categories []string
+}
+
+// SyntheticOptions describe a synthetic dataset
+type SyntheticOptions struct {
+	Rows  int
+	Model SyntheticModel
+	// Label is the header of the label column, "y" if empty
+	Label     string
+	Intercept float64
+	Columns   []SyntheticColumn
+	// Features is the number of numeric columns, named x1 to xn and with
+	// random coefficients and values in [0, 10), generated when Columns is
+	// empty
+	Features int
+	// Noise is the standard deviation of the gaussian noise added to the
+	// linear combination of the features
+	Noise float64
+	// Integer rounds the numeric features and the linear labels, as Prio
+	// only aggregates integers
+	Integer bool
+	Seed    int64
+}
+
+// SyntheticDataset is a dataset generated by a known model
+type SyntheticDataset struct {
+	Columns
+	// Intercept and Coefficients, one per feature, are the true model
+	Intercept    float64
+	Coefficients []float64
+	Points       []MlDataPoint
+	schema       *Schema
+	records      [][]string
+}
+
+// GenerateDataset generates a synthetic dataset described by opts, which
+// must give at least the rows
+func GenerateDataset(opts *SyntheticOptions) (*SyntheticDataset, error) {
+	if opts == nil || opts.Rows <= 0 {
+		return nil, errors.New("synthetic datasets need rows")
+	}
+	model := opts.Model
+	if model == "" {
+		model = Linear
+	}
+	if model != Linear && model != Logistic {
+		return nil, fmt.Errorf("unknown synthetic model %q", model)
+	}
+	r := rand.New(rand.NewSource(opts.Seed))
+	columns := opts.Columns
+	if len(columns) == 0 {
+		if opts.Features <= 0 {
+			return nil, ErrNoFeatures
+		}
+		for j := 1; j <= opts.Features; j++ {
+			columns = append(columns, SyntheticColumn{
+				Name:        "x" + strconv.Itoa(j),
+				Coefficient: math.Round(r.Float64()*100-50) / 10,
+				Max:         10,
+			})
+		}
+	}
+	label := opts.Label
+	if label == "" {
+		label = "y"
+	}
+
+	d := &SyntheticDataset{Intercept: opts.Intercept,
+		schema: &Schema{Label: label}}
+	d.Label = label
+	header := make([]string, 0, len(columns)+1)
+	for _, c := range columns {
+		d.Features = append(d.Features, c.Name)
+		d.Coefficients = append(d.Coefficients, c.Coefficient)
+		header = append(header, c.Name)
+		schema := ColumnSchema{Name: c.Name, Type: Numeric}
+		if len(c.Categories) > 0 {
+			schema = ColumnSchema{Name: c.Name, Type: Categorical,
+				Encoding: Ordinal, Categories: c.Categories}
+		}
+		d.schema.Columns = append(d.schema.Columns, schema)
+	}
+	d.schema.Columns = append(d.schema.Columns,
+		ColumnSchema{Name: label, Type: Numeric})
+	d.records = append(d.records, append(header, label))
+
+	for i := 0; i < opts.Rows; i++ {
+		variables := make([]float64, len(columns))
+		record := make([]string, len(columns)+1)
+		y := opts.Intercept
+		for j, c := range columns {
+			if len(c.Categories) > 0 {
+				k := r.Intn(len(c.Categories))
+				variables[j] = float64(k)
+				record[j] = c.Categories[k]
+			} else {
+				min, max := c.Min, c.Max
+				if min == 0 && max == 0 {
+					max = 1
+				}
+				variables[j] = min + r.Float64()*(max-min)
+				if opts.Integer {
+					variables[j] = math.Round(variables[j])
+				}
+				record[j] = strconv.FormatFloat(variables[j], 'g', -1, 64)
+			}
+			y += c.Coefficient * variables[j]
+		}
+		y += r.NormFloat64() * opts.Noise
+		if model == Logistic {
+			if r.Float64() < 1/(1+math.Exp(-y)) {
+				y = 1
+			} else {
+				y = 0
+			}
+		} else if opts.Integer {
+			y = math.Round(y)
+		}
+		record[len(columns)] = strconv.FormatFloat(y, 'g', -1, 64)
+		d.Points = append(d.Points, MlDataPoint{Label: y, Variables: variables})
+		d.records = append(d.records, record)
+	}
+	return d, nil
+}
+
+// Schema returns the schema loading the csv file of the dataset back into
+// its points
+func (d *SyntheticDataset) Schema() *Schema {
+	return d.schema
+}
+
+// WriteCSV writes the dataset to a csv file with a header
+func (d *SyntheticDataset) WriteCSV(fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(file)
+	w.WriteAll(d.records)
+	if err := w.Error(); err != nil {
+		file.Close()
+		return errors.New("couldn't write dataset: " + err.Error())
+	}
+	return file.Close()
+}
@@ -0,0 +1,72 @@
+package vanilla_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateLinearDataset(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:      500,
+		Intercept: 4,
+		Columns: []vanilla.SyntheticColumn{
+			{Name: "age", Coefficient: 0.5, Min: 20, Max: 80},
+			{Name: "bmi", Coefficient: -2, Min: 18, Max: 35},
+			{Name: "site", Coefficient: 3,
+				Categories: []string{"Coimbra", "Lisbon", "Porto"}},
+		},
+		Noise: 0.1,
+		Seed:  1,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"age", "bmi", "site"}, d.Features)
+	require.Equal(t, 500, len(d.Points))
+
+	r, err := vanilla.VanillaTrainNamedRegressionModel(d.Points, &d.Columns)
+	require.Nil(t, err)
+	require.InDelta(t, d.Intercept, r.Coeff(0), 0.1)
+	for j, c := range d.Coefficients {
+		require.InDelta(t, c, r.Coeff(j+1), 0.01)
+	}
+
+	// The csv file loads back into the same points
+	dir, err := ioutil.TempDir("", "synthetic")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "linear.csv")
+	require.Nil(t, d.WriteCSV(fileName))
+	dataset, err := vanilla.LoadDataset(fileName,
+		&vanilla.LoadOptions{Schema: d.Schema()})
+	require.Nil(t, err)
+	require.Equal(t, d.Columns, dataset.Columns)
+	require.Equal(t, d.Points, dataset.MlDataPoints(""))
+}
+
+func TestGenerateLogisticDataset(t *testing.T) {
+	d, err := vanilla.GenerateDataset(&vanilla.SyntheticOptions{
+		Rows:     200,
+		Model:    vanilla.Logistic,
+		Features: 2,
+		Integer:  true,
+		Seed:     2,
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"x1", "x2"}, d.Features)
+	require.Equal(t, "y", d.Label)
+	for _, p := range d.Points {
+		require.Contains(t, []float64{0, 1}, p.Label)
+		for _, v := range p.Variables {
+			require.Equal(t, float64(int(v)), v)
+		}
+	}
+
+	_, err = vanilla.GenerateDataset(&vanilla.SyntheticOptions{Rows: 10})
+	require.Equal(t, vanilla.ErrNoFeatures, err)
+	_, err = vanilla.GenerateDataset(nil)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,187 @@
+package vanilla
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Model is a trained model
+type Model interface {
+	// Predict returns the label predicted for a point with the given
+	// variables
+	Predict(variables []float64) (float64, error)
+	// Describe returns a human readable description of the model, such as
+	// its formula
+	Describe() string
+	// MarshalBinary encodes the model, which is decoded by the ModelDecoder
+	// registered with its trainer
+	MarshalBinary() ([]byte, error)
+}
+
+// Trainer trains a model on decrypted data points
+type Trainer interface {
+	// Train trains a model on points whose label and features are named by
+	// columns, which may be nil
+	Train(points []MlDataPoint, columns *Columns) (Model, error)
+}
+
+// TrainerFactory creates a trainer configured by params
+type TrainerFactory func(params Params) (Trainer, error)
+
+// ModelDecoder decodes a model encoded by its MarshalBinary method
+type ModelDecoder func(data []byte) (Model, error)
+
+type trainerEntry struct {
+	factory TrainerFactory
+	decoder ModelDecoder
+}
+
+var trainers = struct {
+	sync.Mutex
+	entries map[string]trainerEntry
+}{entries: make(map[string]trainerEntry)}
+
+// RegisterTrainer registers a trainer and the decoder of its models under a
+// name
+func RegisterTrainer(name string, factory TrainerFactory,
+	decoder ModelDecoder) error {
+	trainers.Lock()
+	defer trainers.Unlock()
+	if _, ok := trainers.entries[name]; ok {
+		return errors.New("trainer " + name + " is already registered")
+	}
+	trainers.entries[name] = trainerEntry{factory, decoder}
+	return nil
+}
+
+// lookupTrainer returns the registry entry of a trainer
+func lookupTrainer(name string) (trainerEntry, error) {
+	trainers.Lock()
+	defer trainers.Unlock()
+	entry, ok := trainers.entries[name]
+	if !ok {
+		return entry, errors.New("unknown trainer " + name)
+	}
+	return entry, nil
+}
+
+// NewTrainer creates a registered trainer configured by params, which may
+// be nil
+func NewTrainer(name string, params Params) (Trainer, error) {
+	entry, err := lookupTrainer(name)
+	if err != nil {
+		return nil, err
+	}
+	if params == nil {
+		params = Params{}
+	}
+	return entry.factory(params)
+}
+
+// DecodeModel decodes a model trained by the named trainer
+func DecodeModel(name string, data []byte) (Model, error) {
+	entry, err := lookupTrainer(name)
+	if err != nil {
+		return nil, err
+	}
+	return entry.decoder(data)
+}
+
+// Trainers returns the names of the registered trainers, sorted
+func Trainers() []string {
+	trainers.Lock()
+	defer trainers.Unlock()
+	var names []string
+	for name := range trainers.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Params are the named parameters of a trainer
+type Params map[string]string
+
+// ParseParams parses comma-separated key=value parameters, such as
+// "l2=0.1,maxIterations=50"
+func ParseParams(list string) (Params, error) {
+	params := Params{}
+	for _, pair := range splitNames(list) {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("parameter %q isn't key=value", pair)
+		}
+		params[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+	return params, nil
+}
+
+// Check returns an error if a parameter isn't one of the known ones, which
+// catches misspelled parameters
+func (p Params) Check(known ...string) error {
+	for key := range p {
+		found := false
+		for _, k := range known {
+			found = found || k == key
+		}
+		if !found {
+			return fmt.Errorf("unknown parameter %q, expected one of %v", key,
+				known)
+		}
+	}
+	return nil
+}
+
+// Float returns a float parameter, or def if it isn't set
+func (p Params) Float(key string, def float64) (float64, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %q isn't a number", key)
+	}
+	return f, nil
+}
+
+// Int returns an integer parameter, or def if it isn't set
+func (p Params) Int(key string, def int) (int, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("parameter %q isn't an integer", key)
+	}
+	return i, nil
+}
+
+// Bool returns a boolean parameter, or def if it isn't set
+func (p Params) Bool(key string, def bool) (bool, error) {
+	v, ok := p[key]
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("parameter %q isn't a boolean", key)
+	}
+	return b, nil
+}
+
+// String returns the string form of the parameters, sorted by key, as parsed
+// by ParseParams
+func (p Params) String() string {
+	var pairs []string
+	for key, v := range p {
+		pairs = append(pairs, key+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
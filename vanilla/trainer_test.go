@@ -0,0 +1,99 @@
+package vanilla_test
+
+import (
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainerRegistry(t *testing.T) {
+	require.Contains(t, vanilla.Trainers(), "ols")
+	require.Contains(t, vanilla.Trainers(), "logistic")
+	require.NotNil(t, vanilla.RegisterTrainer("ols", nil, nil))
+
+	_, err := vanilla.NewTrainer("perceptron", nil)
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("ols", vanilla.Params{"l2": "1"})
+	require.NotNil(t, err)
+
+	params, err := vanilla.ParseParams(" negative=1, positive = 2,l2=0.5")
+	require.Nil(t, err)
+	require.Equal(t, vanilla.Params{"negative": "1", "positive": "2",
+		"l2": "0.5"}, params)
+	require.Equal(t, "l2=0.5,negative=1,positive=2", params.String())
+	_, err = vanilla.ParseParams("l2")
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("logistic", vanilla.Params{"l2": "strong"})
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("logistic", vanilla.Params{"l2": "-1"})
+	require.NotNil(t, err)
+	_, err = vanilla.NewTrainer("logistic", vanilla.Params{"positive": "0"})
+	require.NotNil(t, err)
+}
+
+func TestTrainers(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	points := dataset.MlDataPoints("test")
+	r, err := vanilla.VanillaTrainNamedRegressionModel(points,
+		&dataset.Columns)
+	require.Nil(t, err)
+
+	trainer, err := vanilla.NewTrainer("ols", nil)
+	require.Nil(t, err)
+	model, err := trainer.Train(points, &dataset.Columns)
+	require.Nil(t, err)
+	require.Equal(t, r.Formula, model.Describe())
+	predicted, err := model.Predict(points[1].Variables)
+	require.Nil(t, err)
+	expected, err := r.Predict(points[1].Variables)
+	require.Nil(t, err)
+	require.InDelta(t, expected, predicted, 1e-9)
+
+	data, err := model.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("ols", data)
+	require.Nil(t, err)
+	require.Equal(t, model, decoded)
+
+	// Logistic regression of the label of test2 mapped from 1/2
+	trainer, err = vanilla.NewTrainer("logistic", vanilla.Params{
+		"negative": "1", "positive": "2", "l2": "1"})
+	require.Nil(t, err)
+	model, err = trainer.Train(points, &dataset.Columns)
+	require.Nil(t, err)
+	data, err = model.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err = vanilla.DecodeModel("logistic", data)
+	require.Nil(t, err)
+	require.Equal(t, model, decoded)
+	label, err := decoded.Predict(points[0].Variables)
+	require.Nil(t, err)
+	require.Contains(t, []float64{1, 2}, label)
+}
+
+func TestUnscaleModel(t *testing.T) {
+	raw, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	scaler, err := vanilla.FitScaler(vanilla.MinMax, raw.Features,
+		raw.MlDataPoints("test"))
+	require.Nil(t, err)
+	scaled, err := vanilla.LoadDataset("tests/test2.csv",
+		&vanilla.LoadOptions{Scaler: scaler})
+	require.Nil(t, err)
+
+	trainer, err := vanilla.NewTrainer("ols", nil)
+	require.Nil(t, err)
+	model, err := trainer.Train(scaled.MlDataPoints("test"), &scaled.Columns)
+	require.Nil(t, err)
+	unscaled, err := scaler.UnscaleModel(model)
+	require.Nil(t, err)
+	for i, p := range raw.Points {
+		expected, err := model.Predict(scaled.Points[i].Variables)
+		require.Nil(t, err)
+		predicted, err := unscaled.Predict(p.Variables)
+		require.Nil(t, err)
+		require.InDelta(t, expected, predicted, 1e-9)
+	}
+}
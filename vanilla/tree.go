@@ -0,0 +1,667 @@
+package vanilla
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterTrainer("tree", newTreeTrainer, decodeTreeModel)
+	RegisterTrainer("forest", newForestTrainer, decodeForestModel)
+}
+
+// TreeOptions configure the growth of CART decision trees
+type TreeOptions struct {
+	// Classification grows classification trees splitting on the Gini
+	// impurity, instead of regression trees splitting on the variance
+	Classification bool
+	// MaxDepth bounds the depth of the trees, 10 if 0
+	MaxDepth int
+	// MinLeaf is the smallest number of points of a leaf, 1 if 0
+	MinLeaf int
+	// MaxFeatures, if set, is the number of features drawn at random among
+	// which every split is chosen, as in random forests
+	MaxFeatures int
+	// Seed seeds the drawing of the features
+	Seed int64
+}
+
+// TreeNode is a node of a decision tree. Points whose Feature is at most
+// Threshold go to the Left child, the others to the Right one, and leaves
+// have no children.
+type TreeNode struct {
+	Feature   int       `json:",omitempty"`
+	Threshold float64   `json:",omitempty"`
+	Left      *TreeNode `json:",omitempty"`
+	Right     *TreeNode `json:",omitempty"`
+	// Value is the prediction of a leaf, the mean or the most frequent label
+	// of its points
+	Value float64
+	// Counts are the numbers of points of every class of a classification
+	// leaf
+	Counts []int `json:",omitempty"`
+}
+
+// leaf returns the leaf reached by a point
+func (n *TreeNode) leaf(variables []float64) *TreeNode {
+	for n.Left != nil {
+		if variables[n.Feature] <= n.Threshold {
+			n = n.Left
+		} else {
+			n = n.Right
+		}
+	}
+	return n
+}
+
+// treeBuilder grows a decision tree on points
+type treeBuilder struct {
+	options TreeOptions
+	points  []MlDataPoint
+	// classes are the sorted labels of a classification and class the index
+	// of the label of every point
+	classes     []float64
+	class       []int
+	importances []float64
+	random      *rand.Rand
+}
+
+func newTreeBuilder(points []MlDataPoint, classes []float64,
+	opts *TreeOptions) (*treeBuilder, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	b := &treeBuilder{points: points, classes: classes,
+		importances: make([]float64, len(points[0].Variables))}
+	if opts != nil {
+		b.options = *opts
+	}
+	if b.options.MaxDepth == 0 {
+		b.options.MaxDepth = 10
+	}
+	if b.options.MinLeaf == 0 {
+		b.options.MinLeaf = 1
+	}
+	b.random = rand.New(rand.NewSource(b.options.Seed))
+	if b.options.Classification {
+		b.class = make([]int, len(points))
+		for i, p := range points {
+			b.class[i] = sort.SearchFloat64s(classes, p.Label)
+			if b.class[i] == len(classes) || classes[b.class[i]] != p.Label {
+				return nil, fmt.Errorf("%v: %v", ErrUnknownLabel, p.Label)
+			}
+		}
+	}
+	for _, p := range points {
+		if len(p.Variables) != len(b.importances) {
+			return nil, ErrFieldsCount
+		}
+	}
+	return b, nil
+}
+
+// classesOf returns the sorted distinct labels of points
+func classesOf(points []MlDataPoint) []float64 {
+	seen := make(map[float64]bool)
+	var classes []float64
+	for _, p := range points {
+		if !seen[p.Label] {
+			seen[p.Label] = true
+			classes = append(classes, p.Label)
+		}
+	}
+	sort.Float64s(classes)
+	return classes
+}
+
+// impurity returns the Gini impurity of class counts, or the variance of
+// labels of the given sum and sum of squares, times the number of points
+func (b *treeBuilder) impurity(count float64, counts []float64, sum float64,
+	sumSquares float64) float64 {
+	if count == 0 {
+		return 0
+	}
+	if b.options.Classification {
+		gini := count
+		for _, c := range counts {
+			gini -= c * c / count
+		}
+		return gini
+	}
+	return sumSquares - sum*sum/count
+}
+
+// statistics returns the class counts, the sum and the sum of squares of the
+// labels of points given by their index
+func (b *treeBuilder) statistics(indices []int) ([]float64, float64,
+	float64) {
+	counts := make([]float64, len(b.classes))
+	sum, sumSquares := 0.0, 0.0
+	for _, i := range indices {
+		if b.options.Classification {
+			counts[b.class[i]]++
+		}
+		sum += b.points[i].Label
+		sumSquares += b.points[i].Label * b.points[i].Label
+	}
+	return counts, sum, sumSquares
+}
+
+// grow grows the subtree of points given by their index
+func (b *treeBuilder) grow(indices []int, depth int) *TreeNode {
+	counts, sum, sumSquares := b.statistics(indices)
+	n := float64(len(indices))
+	node := &TreeNode{Value: sum / n}
+	if b.options.Classification {
+		node.Counts = make([]int, len(counts))
+		best := 0
+		for c, count := range counts {
+			node.Counts[c] = int(count)
+			if count > counts[best] {
+				best = c
+			}
+		}
+		node.Value = b.classes[best]
+	}
+	parent := b.impurity(n, counts, sum, sumSquares)
+	if depth >= b.options.MaxDepth || len(indices) < 2*b.options.MinLeaf ||
+		parent <= 1e-12 {
+		return node
+	}
+
+	features := b.random.Perm(len(b.importances))
+	if m := b.options.MaxFeatures; m > 0 && m < len(features) {
+		features = features[:m]
+	}
+	bestFeature, bestThreshold, bestImpurity := -1, 0.0, parent
+	sorted := append([]int{}, indices...)
+	for _, j := range features {
+		sort.Slice(sorted, func(x, y int) bool {
+			return b.points[sorted[x]].Variables[j] <
+				b.points[sorted[y]].Variables[j]
+		})
+		leftCounts := make([]float64, len(counts))
+		leftSum, leftSquares := 0.0, 0.0
+		for k := 0; k < len(sorted)-1; k++ {
+			i := sorted[k]
+			if b.options.Classification {
+				leftCounts[b.class[i]]++
+			}
+			leftSum += b.points[i].Label
+			leftSquares += b.points[i].Label * b.points[i].Label
+			left := k + 1
+			v, next := b.points[i].Variables[j],
+				b.points[sorted[k+1]].Variables[j]
+			if v == next || left < b.options.MinLeaf ||
+				len(sorted)-left < b.options.MinLeaf {
+				continue
+			}
+			rightCounts := make([]float64, len(counts))
+			for c := range counts {
+				rightCounts[c] = counts[c] - leftCounts[c]
+			}
+			impurity := b.impurity(float64(left), leftCounts, leftSum,
+				leftSquares) + b.impurity(float64(len(sorted)-left),
+				rightCounts, sum-leftSum, sumSquares-leftSquares)
+			if impurity < bestImpurity-1e-12 {
+				bestFeature, bestThreshold, bestImpurity = j, (v+next)/2,
+					impurity
+			}
+		}
+	}
+	if bestFeature < 0 {
+		return node
+	}
+
+	b.importances[bestFeature] += parent - bestImpurity
+	var left, right []int
+	for _, i := range indices {
+		if b.points[i].Variables[bestFeature] <= bestThreshold {
+			left = append(left, i)
+		} else {
+			right = append(right, i)
+		}
+	}
+	node.Feature, node.Threshold = bestFeature, bestThreshold
+	node.Left = b.grow(left, depth+1)
+	node.Right = b.grow(right, depth+1)
+	return node
+}
+
+// normalize scales importances to sum to 1
+func normalize(importances []float64) []float64 {
+	total := 0.0
+	for _, v := range importances {
+		total += v
+	}
+	normalized := make([]float64, len(importances))
+	for j, v := range importances {
+		if total > 0 {
+			normalized[j] = v / total
+		}
+	}
+	return normalized
+}
+
+// DecisionTreeModel is a CART decision tree
+type DecisionTreeModel struct {
+	Columns
+	Classification bool
+	// Classes are the sorted labels of a classification tree
+	Classes []float64 `json:",omitempty"`
+	Root    *TreeNode
+	// Importances are the total impurity decreases of the splits on every
+	// feature, normalized to sum to 1
+	Importances []float64
+}
+
+// TrainDecisionTree grows a decision tree on points. columns and opts may be
+// nil.
+func TrainDecisionTree(points []MlDataPoint, columns *Columns,
+	opts *TreeOptions) (*DecisionTreeModel, error) {
+	m := &DecisionTreeModel{}
+	if opts != nil && opts.Classification {
+		m.Classification = true
+		m.Classes = classesOf(points)
+	}
+	b, err := newTreeBuilder(points, m.Classes, opts)
+	if err != nil {
+		return nil, err
+	}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	indices := make([]int, len(points))
+	for i := range indices {
+		indices[i] = i
+	}
+	m.Root = b.grow(indices, 0)
+	m.Importances = normalize(b.importances)
+	return m, nil
+}
+
+// checkVariables returns an error if a point doesn't have the features of a
+// model
+func checkVariables(variables []float64, importances []float64) error {
+	if len(variables) != len(importances) {
+		return ErrFieldsCount
+	}
+	return nil
+}
+
+// Predict implements Model
+func (m *DecisionTreeModel) Predict(variables []float64) (float64, error) {
+	if err := checkVariables(variables, m.Importances); err != nil {
+		return 0, err
+	}
+	return m.Root.leaf(variables).Value, nil
+}
+
+// Probability returns the fraction of the points of the leaf of a point that
+// have the positive label, the largest, of a binary classification tree
+func (m *DecisionTreeModel) Probability(variables []float64) (float64,
+	error) {
+	if len(m.Classes) != 2 {
+		return 0, fmt.Errorf("tree has %d classes, not 2", len(m.Classes))
+	}
+	if err := checkVariables(variables, m.Importances); err != nil {
+		return 0, err
+	}
+	counts := m.Root.leaf(variables).Counts
+	return float64(counts[1]) / float64(counts[0]+counts[1]), nil
+}
+
+// BinaryLabels implements BinaryClassifier, the labels of a binary
+// classification tree being its smallest and largest labels
+func (m *DecisionTreeModel) BinaryLabels() LabelMapping {
+	return binaryLabels(m.Classes)
+}
+
+// ClassLabels implements Classifier, a regression tree having no class labels
+func (m *DecisionTreeModel) ClassLabels() []float64 {
+	return m.Classes
+}
+
+// Binary tells if the tree classifies two labels
+func (m *DecisionTreeModel) Binary() bool {
+	return len(m.Classes) == 2
+}
+
+func binaryLabels(classes []float64) LabelMapping {
+	if len(classes) == 0 {
+		return LabelMapping{}
+	}
+	return LabelMapping{Negative: classes[0],
+		Positive: classes[len(classes)-1]}
+}
+
+// depth returns the depth of a subtree
+func (n *TreeNode) depth() int {
+	if n.Left == nil {
+		return 0
+	}
+	left, right := n.Left.depth(), n.Right.depth()
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
+
+// Describe implements Model, it returns the depth and the feature
+// importances of the tree
+func (m *DecisionTreeModel) Describe() string {
+	return fmt.Sprintf("Decision tree of depth %d, importances %s",
+		m.Root.depth(), formatImportances(m.Features, m.Importances))
+}
+
+// Summary returns the feature importances of the tree, from the most
+// important
+func (m *DecisionTreeModel) Summary() string {
+	return importanceTable(m.Features, m.Importances)
+}
+
+// formatImportances lists the importances of the features, naming them by
+// their index if names is empty
+func formatImportances(names []string, importances []float64) string {
+	parts := make([]string, len(importances))
+	for j, v := range importances {
+		parts[j] = fmt.Sprintf("%v=%.3f", featureName(names, j), v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func featureName(names []string, j int) string {
+	if j < len(names) {
+		return names[j]
+	}
+	return fmt.Sprintf("X%d", j)
+}
+
+// importanceTable formats the importances of the features, sorted by
+// decreasing importance
+func importanceTable(names []string, importances []float64) string {
+	order := make([]int, len(importances))
+	for j := range order {
+		order[j] = j
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return importances[order[a]] > importances[order[b]]
+	})
+	rows := []string{fmt.Sprintf("%-16s %10s", "Feature", "Importance")}
+	for _, j := range order {
+		rows = append(rows, fmt.Sprintf("%-16s %10.4f",
+			featureName(names, j), importances[j]))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// MarshalBinary implements Model
+func (m *DecisionTreeModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeTreeModel(data []byte) (Model, error) {
+	m := &DecisionTreeModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode decision tree: " + err.Error())
+	}
+	if m.Root == nil {
+		return nil, errors.New("decision tree has no root")
+	}
+	return m, nil
+}
+
+// RandomForestModel is a bagged forest of decision trees, which average
+// their predictions or vote for a class
+type RandomForestModel struct {
+	Columns
+	Classification bool
+	Classes        []float64 `json:",omitempty"`
+	Trees          []*TreeNode
+	// Importances are the mean importances of the trees
+	Importances []float64
+}
+
+// ForestOptions configure a random forest
+type ForestOptions struct {
+	TreeOptions
+	// Trees is the number of trees, 50 if 0
+	Trees int
+}
+
+// TrainRandomForest grows every tree of a forest on a bootstrap sample of
+// points, splitting on MaxFeatures features drawn at random, the square root
+// of the number of features for classifications and a third of them for
+// regressions if 0. columns and opts may be nil.
+func TrainRandomForest(points []MlDataPoint, columns *Columns,
+	opts *ForestOptions) (*RandomForestModel, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
+	var options ForestOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Trees == 0 {
+		options.Trees = 50
+	}
+	d := len(points[0].Variables)
+	if options.MaxFeatures == 0 {
+		options.MaxFeatures = int(math.Max(1, float64(d)/3))
+		if options.Classification {
+			options.MaxFeatures = int(math.Max(1, math.Sqrt(float64(d))))
+		}
+	}
+	m := &RandomForestModel{Classification: options.Classification,
+		Importances: make([]float64, d)}
+	if columns != nil {
+		m.Columns = *columns
+	}
+	if m.Classification {
+		m.Classes = classesOf(points)
+	}
+	r := rand.New(rand.NewSource(options.Seed))
+	sample := make([]MlDataPoint, len(points))
+	for t := 0; t < options.Trees; t++ {
+		for i := range sample {
+			sample[i] = points[r.Intn(len(points))]
+		}
+		treeOptions := options.TreeOptions
+		treeOptions.Seed = r.Int63()
+		b, err := newTreeBuilder(sample, m.Classes, &treeOptions)
+		if err != nil {
+			return nil, err
+		}
+		indices := make([]int, len(sample))
+		for i := range indices {
+			indices[i] = i
+		}
+		m.Trees = append(m.Trees, b.grow(indices, 0))
+		for j, v := range normalize(b.importances) {
+			m.Importances[j] += v / float64(options.Trees)
+		}
+	}
+	return m, nil
+}
+
+// Predict implements Model
+func (m *RandomForestModel) Predict(variables []float64) (float64, error) {
+	if err := checkVariables(variables, m.Importances); err != nil {
+		return 0, err
+	}
+	if !m.Classification {
+		sum := 0.0
+		for _, tree := range m.Trees {
+			sum += tree.leaf(variables).Value
+		}
+		return sum / float64(len(m.Trees)), nil
+	}
+	votes := make([]int, len(m.Classes))
+	for _, tree := range m.Trees {
+		votes[sort.SearchFloat64s(m.Classes, tree.leaf(variables).Value)]++
+	}
+	best := 0
+	for c := range votes {
+		if votes[c] > votes[best] {
+			best = c
+		}
+	}
+	return m.Classes[best], nil
+}
+
+// Probability returns the mean over the trees of the fraction of the points
+// of the leaf of a point that have the positive label, the largest, of a
+// binary classification forest
+func (m *RandomForestModel) Probability(variables []float64) (float64,
+	error) {
+	if len(m.Classes) != 2 {
+		return 0, fmt.Errorf("forest has %d classes, not 2", len(m.Classes))
+	}
+	if err := checkVariables(variables, m.Importances); err != nil {
+		return 0, err
+	}
+	sum := 0.0
+	for _, tree := range m.Trees {
+		counts := tree.leaf(variables).Counts
+		sum += float64(counts[1]) / float64(counts[0]+counts[1])
+	}
+	return sum / float64(len(m.Trees)), nil
+}
+
+// BinaryLabels implements BinaryClassifier, the labels of a binary
+// classification forest being its smallest and largest labels
+func (m *RandomForestModel) BinaryLabels() LabelMapping {
+	return binaryLabels(m.Classes)
+}
+
+// ClassLabels implements Classifier, a regression forest having no class
+// labels
+func (m *RandomForestModel) ClassLabels() []float64 {
+	return m.Classes
+}
+
+// Binary tells if the forest classifies two labels
+func (m *RandomForestModel) Binary() bool {
+	return len(m.Classes) == 2
+}
+
+// Describe implements Model, it returns the number of trees and the feature
+// importances of the forest
+func (m *RandomForestModel) Describe() string {
+	return fmt.Sprintf("Random forest of %d trees, importances %s",
+		len(m.Trees), formatImportances(m.Features, m.Importances))
+}
+
+// Summary returns the feature importances of the forest, from the most
+// important
+func (m *RandomForestModel) Summary() string {
+	return importanceTable(m.Features, m.Importances)
+}
+
+// MarshalBinary implements Model
+func (m *RandomForestModel) MarshalBinary() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func decodeForestModel(data []byte) (Model, error) {
+	m := &RandomForestModel{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.New("couldn't decode random forest: " + err.Error())
+	}
+	if len(m.Trees) == 0 {
+		return nil, errors.New("random forest has no tree")
+	}
+	return m, nil
+}
+
+// treeOptions parses the TreeOptions parameters of a tree or forest trainer
+func treeOptions(params Params) (TreeOptions, error) {
+	var o TreeOptions
+	var seed int
+	var err error
+	o.Classification, err = params.Bool("classification", false)
+	if err == nil {
+		o.MaxDepth, err = params.Int("maxDepth", 0)
+	}
+	if err == nil {
+		o.MinLeaf, err = params.Int("minLeaf", 0)
+	}
+	if err == nil {
+		o.MaxFeatures, err = params.Int("maxFeatures", 0)
+	}
+	if err == nil {
+		seed, err = params.Int("seed", 0)
+	}
+	if err != nil {
+		return o, err
+	}
+	if o.MaxDepth < 0 || o.MinLeaf < 0 || o.MaxFeatures < 0 {
+		return o, errors.New("maxDepth, minLeaf and maxFeatures can't be " +
+			"negative")
+	}
+	o.Seed = int64(seed)
+	return o, nil
+}
+
+// TreeTrainer grows CART decision trees. Its parameters are the TreeOptions
+// classification, false by default, maxDepth, minLeaf, maxFeatures and seed.
+type TreeTrainer struct {
+	Options TreeOptions
+}
+
+func newTreeTrainer(params Params) (Trainer, error) {
+	err := params.Check("classification", "maxDepth", "minLeaf",
+		"maxFeatures", "seed")
+	if err != nil {
+		return nil, err
+	}
+	options, err := treeOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	return &TreeTrainer{Options: options}, nil
+}
+
+// Train implements Trainer
+func (t *TreeTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	return TrainDecisionTree(points, columns, &t.Options)
+}
+
+// ForestTrainer grows random forests. Its parameters are the parameters of
+// TreeTrainer and the number of trees.
+type ForestTrainer struct {
+	Options ForestOptions
+}
+
+func newForestTrainer(params Params) (Trainer, error) {
+	err := params.Check("classification", "maxDepth", "minLeaf",
+		"maxFeatures", "seed", "trees")
+	if err != nil {
+		return nil, err
+	}
+	t := &ForestTrainer{}
+	t.Options.TreeOptions, err = treeOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	t.Options.Trees, err = params.Int("trees", 0)
+	if err != nil {
+		return nil, err
+	}
+	if t.Options.Trees < 0 {
+		return nil, errors.New("trees can't be negative")
+	}
+	return t, nil
+}
+
+// Train implements Trainer
+func (t *ForestTrainer) Train(points []MlDataPoint, columns *Columns) (Model,
+	error) {
+	return TrainRandomForest(points, columns, &t.Options)
+}
@@ -0,0 +1,131 @@
+package vanilla_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/dedis/student_18_ml/vanilla"
+	"github.com/stretchr/testify/require"
+)
+
+// interactions returns points whose label is given by the interaction of x1
+// and x2, x3 being noise, as classes 1 and 2 or as the product of x1 and x2
+func interactions(n int, classification bool) []vanilla.MlDataPoint {
+	r := rand.New(rand.NewSource(9))
+	points := make([]vanilla.MlDataPoint, n)
+	for i := range points {
+		x1, x2, x3 := 2*r.Float64()-1, 2*r.Float64()-1, 2*r.Float64()-1
+		label := x1 * x2
+		if classification {
+			label = 1
+			if x1*x2 > 0 {
+				label = 2
+			}
+		}
+		points[i] = vanilla.MlDataPoint{Label: label,
+			Variables: []float64{x1, x2, x3}}
+	}
+	return points
+}
+
+func TestDecisionTree(t *testing.T) {
+	points := interactions(1000, true)
+	train, test := points[:800], points[800:]
+	columns := &vanilla.Columns{Label: "y",
+		Features: []string{"x1", "x2", "x3"}}
+	tree, err := vanilla.TrainDecisionTree(train, columns,
+		&vanilla.TreeOptions{Classification: true})
+	require.Nil(t, err)
+	evaluation, err := vanilla.EvaluateModel(tree, test)
+	require.Nil(t, err)
+	require.True(t, evaluation.Classification.Accuracy > 0.9)
+	require.True(t, tree.Importances[2] < 0.05)
+	require.InDelta(t, 1, tree.Importances[0]+tree.Importances[1]+
+		tree.Importances[2], 1e-9)
+	require.Contains(t, tree.Describe(), "x3=")
+
+	// A linear model can't separate the classes
+	logistic, err := trainModel(t, "logistic",
+		vanilla.Params{"negative": "1", "positive": "2"}, train)
+	require.Nil(t, err)
+	evaluation, err = vanilla.EvaluateModel(logistic, test)
+	require.Nil(t, err)
+	require.True(t, evaluation.Classification.Accuracy < 0.7)
+
+	// The depth and the leaf size bound the tree
+	stump, err := vanilla.TrainDecisionTree(train, nil,
+		&vanilla.TreeOptions{Classification: true, MaxDepth: 1})
+	require.Nil(t, err)
+	require.Nil(t, stump.Root.Left.Left)
+	leaves, err := vanilla.TrainDecisionTree(train, nil,
+		&vanilla.TreeOptions{Classification: true, MinLeaf: 300})
+	require.Nil(t, err)
+	require.Nil(t, leaves.Root.Left.Left)
+
+	model, err := trainModel(t, "tree", vanilla.Params{
+		"classification": "true", "maxDepth": "8"}, train)
+	require.Nil(t, err)
+	data, err := model.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("tree", data)
+	require.Nil(t, err)
+	require.Equal(t, model, decoded)
+
+	_, err = tree.Predict([]float64{1})
+	require.Equal(t, vanilla.ErrFieldsCount, err)
+	_, err = vanilla.NewTrainer("tree", vanilla.Params{"classification": "maybe"})
+	require.NotNil(t, err)
+}
+
+func TestRegressionTree(t *testing.T) {
+	points := interactions(1000, false)
+	tree, err := trainModel(t, "tree", vanilla.Params{"maxDepth": "6",
+		"minLeaf": "5"}, points[:800])
+	require.Nil(t, err)
+	evaluation, err := vanilla.EvaluateModel(tree, points[800:])
+	require.Nil(t, err)
+	require.True(t, evaluation.Regression.R2 > 0.8)
+
+	// The product has no linear trend
+	ols, err := trainModel(t, "ols", nil, points[:800])
+	require.Nil(t, err)
+	evaluation, err = vanilla.EvaluateModel(ols, points[800:])
+	require.Nil(t, err)
+	require.True(t, evaluation.Regression.R2 < 0.1)
+}
+
+func TestRandomForest(t *testing.T) {
+	points := interactions(1000, true)
+	train, test := points[:800], points[800:]
+	forest, err := vanilla.TrainRandomForest(train, nil,
+		&vanilla.ForestOptions{Trees: 20, TreeOptions: vanilla.TreeOptions{
+			Classification: true, MaxFeatures: 2, Seed: 3}})
+	require.Nil(t, err)
+	require.Equal(t, 20, len(forest.Trees))
+	evaluation, err := vanilla.EvaluateModel(forest, test)
+	require.Nil(t, err)
+	require.True(t, evaluation.Classification.Accuracy > 0.9)
+	require.NotNil(t, evaluation.Classification.AUC)
+	require.True(t, forest.Importances[2] < forest.Importances[0])
+	require.Contains(t, forest.Summary(), "Importance")
+
+	// The seed makes the forest reproducible
+	model, err := trainModel(t, "forest", vanilla.Params{
+		"classification": "true", "trees": "5", "seed": "4"}, train)
+	require.Nil(t, err)
+	again, err := trainModel(t, "forest", vanilla.Params{
+		"classification": "true", "trees": "5", "seed": "4"}, train)
+	require.Nil(t, err)
+	require.Equal(t, model, again)
+	data, err := model.MarshalBinary()
+	require.Nil(t, err)
+	decoded, err := vanilla.DecodeModel("forest", data)
+	require.Nil(t, err)
+	require.Equal(t, model, decoded)
+
+	regression, err := vanilla.TrainRandomForest(interactions(500, false),
+		nil, &vanilla.ForestOptions{Trees: 10})
+	require.Nil(t, err)
+	_, err = regression.Probability([]float64{0, 0, 0})
+	require.NotNil(t, err)
+}
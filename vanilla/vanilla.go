@@ -1,12 +1,7 @@
 package vanilla
 
 import (
-	"log"
 	"github.com/sajari/regression"
-	"os"
-	"encoding/csv"
-	"strconv"
-	"math/rand"
 	"errors"
 	"github.com/dedis/cothority/darc"
 	"encoding/json"
@@ -15,75 +10,60 @@ import (
 	"github.com/dedis/cothority/calypso"
 )
 
-func init(){
-	rand.Seed(42)
-}
-
-func handleError(err error) {
-	if err != nil{
-		log.Panic("Error:", err)
-	}
-}
-
 //GetDataPointsFromCSV returns DataPoints with the data points contained
-//in a csv file whose path is given by a string
+//in a csv file whose path is given by a string. Errors are *LoadError
+//naming the file, row and column at fault.
 func GetDataPointsFromCSV(fileName string) (regression.DataPoints, error) {
-	file, err := os.Open(fileName)
-	handleError(err)
-	defer file.Close()
-	//Create a new csv reader
-	reader := csv.NewReader(file)
-	headers, err := reader.Read()
-	handleError(err)
-	records, err := reader.ReadAll()
-	handleError(err)
-	fieldsCount := reader.FieldsPerRecord
-	instancesCount := len(records)
-	log.Printf("Read %d instances with %d fields\n", instancesCount, fieldsCount)
-	//Check that the records' fields count is the same as the headers fields count
-	if fieldsCount != len(headers) {
-		log.Panic("Fields count must be the same as the headers fields count\n")
-		return nil, errors.New(
-			"Fields count must be the same as the headers fields count")
+	dataset, err := LoadDataset(fileName, nil)
+	if err != nil {
+		return nil, err
 	}
-	//Initialize the features dataset
-	features := make([][]float64, instancesCount)
-
-	//Convert from string to float64 all the entries
-	for i, e := range records {
-		row := make([]float64, len(e))
-		for j, v := range e {
-			//TODO(islam): Make this tolerate whitespaces
-			row[j], err = strconv.ParseFloat(v, 64)
-			handleError(err)
-		}
-		features[i] = row
-	}
-	//Create and return the data points
-	return regression.MakeDataPoints(features, fieldsCount-1), nil
+	return dataset.Points, nil
 }
 
 //TrainRegressionModel trains a regression model given dataPoints
 func TrainRegressionModel(points regression.DataPoints) (*regression.Regression,
 	error) {
+	return TrainNamedRegressionModel(points, nil)
+}
+
+//TrainNamedRegressionModel trains a regression model given dataPoints whose
+//label and features are named by columns, which may be nil
+func TrainNamedRegressionModel(points regression.DataPoints,
+	columns *Columns) (*regression.Regression, error) {
 	r := new(regression.Regression)
+	if columns != nil {
+		columns.NameRegression(r)
+	}
 	for _, p := range points {
 		r.Train(p)
 	}
 	err := r.Run()
-	handleError(err)
+	if err != nil {
+		return nil, err
+	}
 	return r, nil;
 }
 
 //TrainRegressionModel trains a regression model given MlDataPoints
 func VanillaTrainRegressionModel(points []MlDataPoint) (*regression.Regression,
 	error) {
+	return VanillaTrainNamedRegressionModel(points, nil)
+}
+
+//VanillaTrainNamedRegressionModel trains a regression model given
+//MlDataPoints whose label and features are named by columns, which may be nil
+func VanillaTrainNamedRegressionModel(points []MlDataPoint,
+	columns *Columns) (*regression.Regression, error) {
+	if len(points) == 0 {
+		return nil, errors.New("no points to train on")
+	}
 	features := make([][]float64, len(points))
 	for i, p := range points {
 		features[i] = append(p.Variables, p.Label)
 	}
-	return TrainRegressionModel(
-		regression.MakeDataPoints(features, len(features[0]) - 1))
+	return TrainNamedRegressionModel(
+		regression.MakeDataPoints(features, len(features[0]) - 1), columns)
 }
 
 // AssociateProviders creates data provider identities and associate
@@ -100,28 +80,42 @@ func AssociateProviders(providers []darc.Identity,
 
 	for i, point := range points {
 		p := &MlDataPoint{desc, point.Observed, point.Variables}
-		bytesBuffer := new(bytes.Buffer)
-		encoder := json.NewEncoder(bytesBuffer)
-		err := encoder.Encode(p)
+		var err error
+		secrets[i], darcs[i], err = AssociateProvider(providers[i], p, i,
+			consumer)
 		if err != nil{
-			return nil, nil, errors.New(
-				"couldn't encode data point: " + err.Error())
-		}
-		secrets[i] = bytesBuffer.Bytes()
-		//Create a similar darc with write access to the provider
-		darcs[i] = darc.NewDarc(darc.InitRules([]darc.Identity{providers[i]},
-			[]darc.Identity{providers[i]}), []byte("Provider" + string(i)))
-		// provider1 is the owner, while reader1 is allowed to do read
-		darcs[i].Rules.AddRule(darc.Action("spawn:"+calypso.ContractWriteID),
-			expression.InitOrExpr(providers[i].String()))
-		if consumer != nil {
-			darcs[i].Rules.AddRule(darc.Action("spawn:"+calypso.ContractReadID),
-				expression.InitOrExpr(consumer.String()))
+			return nil, nil, err
 		}
 	}
 	return &secrets, darcs, nil
 }
 
+// AssociateProvider associates a single training point, the index-th one,
+// with a data provider. It returns the secret to be written to Calypso and
+// the darc controlling it, readable by the consumer when it isn't nil.
+func AssociateProvider(provider darc.Identity, point *MlDataPoint, index int,
+	consumer *darc.Identity) ([]byte, *darc.Darc, error) {
+	bytesBuffer := new(bytes.Buffer)
+	encoder := json.NewEncoder(bytesBuffer)
+	err := encoder.Encode(point)
+	if err != nil{
+		return nil, nil, errors.New(
+			"couldn't encode data point: " + err.Error())
+	}
+	//Create a similar darc with write access to the provider
+	d := darc.NewDarc(darc.InitRules([]darc.Identity{provider},
+		[]darc.Identity{provider}),
+		[]byte("Provider" + string(rune(index))))
+	// provider1 is the owner, while reader1 is allowed to do read
+	d.Rules.AddRule(darc.Action("spawn:"+calypso.ContractWriteID),
+		expression.InitOrExpr(provider.String()))
+	if consumer != nil {
+		d.Rules.AddRule(darc.Action("spawn:"+calypso.ContractReadID),
+			expression.InitOrExpr(consumer.String()))
+	}
+	return bytesBuffer.Bytes(), d, nil
+}
+
 // GetIdentitiesFromSigners gets identities from signers
 // TODO(islam): This function isn't specific to vanilla.
 // Either port to cothority repo or find another way to do it
@@ -40,6 +40,76 @@ func TestGetDataPointsFromCSV(t *testing.T) {
 	require.Equal(t, points[4].Observed, 3.0)
 }
 
+func TestGetDataPointsFromCSVWhitespaces(t *testing.T) {
+	points, err := vanilla.GetDataPointsFromCSV("tests/whitespace.csv")
+	require.Nil(t, err)
+	require.Equal(t, 2, len(points))
+	require.Equal(t, []float64{12.5, 3, 4}, points[0].Variables)
+	require.Equal(t, 5.0, points[0].Observed)
+}
+
+func TestGetDataPointsFromCSVErrors(t *testing.T) {
+	_, err := vanilla.GetDataPointsFromCSV("tests/malformed.csv")
+	require.NotNil(t, err)
+	loadErr, ok := err.(*vanilla.LoadError)
+	require.True(t, ok)
+	require.Equal(t, "tests/malformed.csv", loadErr.File)
+	require.Equal(t, 3, loadErr.Row)
+	require.Equal(t, "field2", loadErr.Column)
+
+	_, err = vanilla.GetDataPointsFromCSV("tests/missing.csv")
+	require.NotNil(t, err)
+	loadErr, ok = err.(*vanilla.LoadError)
+	require.True(t, ok)
+	require.Equal(t, 0, loadErr.Row)
+}
+
+func TestLoadDatasetLenient(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/malformed.csv",
+		&vanilla.LoadOptions{Lenient: true})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(dataset.Points))
+	require.Equal(t, 3.0, dataset.Points[1].Observed)
+	require.Equal(t, 2, len(dataset.Rejected))
+	require.Equal(t, 3, dataset.Rejected[0].Row)
+	require.Equal(t, "field2", dataset.Rejected[0].Column)
+	require.Equal(t, 4, dataset.Rejected[1].Row)
+	require.Equal(t, vanilla.ErrFieldsCount, dataset.Rejected[1].Err)
+}
+
+func TestLoadDatasetColumns(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	require.Equal(t, "label", dataset.Label)
+	require.Equal(t, []string{"field1", "field2", "field3"}, dataset.Features)
+
+	dataset, err = vanilla.LoadDataset("tests/test2.csv",
+		&vanilla.LoadOptions{Label: "field1", Features: []string{"label",
+			"field3", "field2"}, Exclude: []string{"field2"}})
+	require.Nil(t, err)
+	require.Equal(t, "field1", dataset.Label)
+	require.Equal(t, []string{"label", "field3"}, dataset.Features)
+	require.Equal(t, 12.5, dataset.Points[0].Observed)
+	require.Equal(t, []float64{1, 4}, dataset.Points[0].Variables)
+
+	_, err = vanilla.LoadDataset("tests/test2.csv",
+		&vanilla.LoadOptions{Label: "Classification"})
+	require.NotNil(t, err)
+	require.Equal(t, vanilla.ErrUnknownColumn, err.(*vanilla.LoadError).Err)
+	require.Equal(t, "Classification", err.(*vanilla.LoadError).Column)
+}
+
+func TestTrainNamedRegressionModel(t *testing.T) {
+	dataset, err := vanilla.LoadDataset("tests/test2.csv", nil)
+	require.Nil(t, err)
+	r, err := vanilla.TrainNamedRegressionModel(dataset.Points,
+		&dataset.Columns)
+	require.Nil(t, err)
+	require.Equal(t, "label", r.GetObserved())
+	require.Equal(t, "field2", r.GetVar(1))
+	require.Contains(t, r.Formula, "field3")
+}
+
 func TestVanilla(t *testing.T) {
 
 }